@@ -2,6 +2,7 @@ package app
 
 import (
 	"github.com/xdave/keyid/client"
+	"github.com/xdave/keyid/engine"
 	"github.com/xdave/keyid/events"
 	"github.com/xdave/keyid/interfaces"
 	"github.com/xdave/keyid/mediator"
@@ -11,6 +12,7 @@ import (
 
 var Module = fx.Module("app",
 	client.Module,
+	engine.Module,
 	mediator.Module,
 	events.Module,
 	fx.Invoke(func(publisher interfaces.NotificationPublisher) {
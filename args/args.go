@@ -2,8 +2,10 @@ package args
 
 import (
 	"flag"
+	"time"
 
 	"github.com/xdave/keyid/interfaces"
+	"github.com/xdave/keyid/models"
 )
 
 type Args struct {
@@ -13,8 +15,25 @@ type Args struct {
 	Tags        string
 	ExcludeTags string
 	Playlist    string
+	Rules       string
+	Source      interfaces.Source
+	XML         string
+	NML         string
+	Serato      string
+	Engine      string
+	Mixxx       string
+	Folder      string
+	Timeout     time.Duration
 	Random      bool
 	M3U         bool
+	NoCache     bool
+	KeyLock     bool
+	Tempo       string
+	Tolerance   float64
+	HalfTime    bool
+	ThreeFour   bool
+	KeyNotation string
+	UnknownKeys bool
 	Debug       bool
 }
 
@@ -27,14 +46,31 @@ func NewArgs() *Args {
 }
 
 func (a *Args) Parse() {
-	flag.StringVar(&a.Mode, "mode", "suggest", "One of 'suggest' or 'generate'")
+	flag.StringVar(&a.Mode, "mode", "suggest", "One of 'suggest', 'generate' or 'keys' (list the tracks with a missing or unreadable key)")
 	flag.StringVar(&a.From, "from", "1970-01-01", "Only look at tracks newer than this date")
 	flag.StringVar(&a.StartWith, "startWith", "", "Some part of the Track Title to start with in 'generate' mode (otherwise starts with first track in provided 'playlist')")
 	flag.StringVar(&a.Tags, "tags", "", "Only include tracks that match the given tags (comma-separated)")
 	flag.StringVar(&a.ExcludeTags, "excludeTags", "", "Exclude tracks that match the given tags (comma-separated)")
-	flag.StringVar(&a.Playlist, "playlist", "", "Name of Rekordbox Playlist to use (uses whole collection by default)")
+	flag.StringVar(&a.Playlist, "playlist", "", "Name of the playlist, crate or folder to use, depending on the source (uses the whole collection by default)")
+	flag.StringVar(&a.Rules, "rules", "keyid", "Harmonic rule set: 'strict', 'classic', 'keyid' or 'adventurous', or a comma-separated list of key moves like 'same,+1,-1,relative'")
+	flag.StringVar(&a.Source, "source", "", "Where to read tracks from: 'rekordbox', 'xml', 'traktor', 'serato', 'engine', 'mixxx' or 'folder' (defaults to the source whose path flag is set, otherwise 'rekordbox')")
+	flag.StringVar(&a.XML, "xml", "", "Path to a rekordbox collection.xml export ('xml' source)")
+	flag.StringVar(&a.NML, "nml", "", "Path to a Traktor collection.nml ('traktor' source)")
+	flag.StringVar(&a.Serato, "serato", "", "Path to a Serato '_Serato_' folder ('serato' source, default ~/Music/_Serato_)")
+	flag.StringVar(&a.Engine, "engine", "", "Path to an Engine DJ 'Engine Library' folder ('engine' source, default ~/Music/Engine Library)")
+	flag.StringVar(&a.Mixxx, "mixxx", "", "Path to a Mixxx mixxxdb.sqlite ('mixxx' source, default is Mixxx's settings folder)")
+	flag.StringVar(&a.Folder, "folder", "", "Path to a music folder to scan for tagged files ('folder' source, default ~/Music)")
+	flag.DurationVar(&a.Timeout, "timeout", 0, "Give up loading, suggesting or generating after this long, e.g. '30s' (no limit by default)")
 	flag.BoolVar(&a.Random, "random", false, "Randomize playlist before 'generate'")
 	flag.BoolVar(&a.M3U, "m3u", false, "Generate an M3U playlist in 'generate' mode")
+	flag.BoolVar(&a.NoCache, "noCache", false, "Read every track from the source instead of the track cache, and don't update the cache")
+	flag.StringVar(&a.Tempo, "tempo", models.Pitch6.Name, "Pitch fader range to mix with: 'no-pitch', '6%', '10%' or '16%'")
+	flag.Float64Var(&a.Tolerance, "tolerance", models.DefaultTempoTolerance, "How far apart, in percent, BPMs can be and still mix once pitched")
+	flag.BoolVar(&a.KeyLock, "keyLock", false, "Mix with key lock on, so playing a track faster or slower never changes its key")
+	flag.BoolVar(&a.HalfTime, "halfTime", false, "Also mix tracks at half or double the tempo, like 170 BPM drum & bass with 85")
+	flag.BoolVar(&a.ThreeFour, "threeFour", false, "Also mix tracks at 1.5 times or two thirds of the tempo, for a 3/4 feel")
+	flag.StringVar(&a.KeyNotation, "keyNotation", "camelot", "How to write keys: 'camelot' (8A), 'openkey' (1m), 'sharps' (F#m), 'flats' (Gbm) or 'long' (F# minor)")
+	flag.BoolVar(&a.UnknownKeys, "unknownKeys", false, "Also suggest and generate with tracks whose key is missing or unreadable, matched by BPM alone and scored below any key move")
 	flag.BoolVar(&a.Debug, "debug", false, "Enable debug logging")
 
 	flag.Parse()
@@ -42,5 +78,27 @@ func (a *Args) Parse() {
 }
 
 func (a *Args) Validate() {
-	// TODO
+	if a.Source == "" {
+		a.Source = a.inferSource()
+	}
+}
+
+// inferSource picks the source whose library path was given, so '-xml path'
+// works without also passing '-source xml'.
+func (a *Args) inferSource() interfaces.Source {
+	switch {
+	case a.XML != "":
+		return interfaces.SourceXML
+	case a.NML != "":
+		return interfaces.SourceTraktor
+	case a.Serato != "":
+		return interfaces.SourceSerato
+	case a.Engine != "":
+		return interfaces.SourceEngine
+	case a.Mixxx != "":
+		return interfaces.SourceMixxx
+	case a.Folder != "":
+		return interfaces.SourceFolder
+	}
+	return interfaces.SourceRekordbox
 }
@@ -0,0 +1,25 @@
+package client
+
+import (
+	"slices"
+
+	"github.com/xdave/keyid/interfaces"
+
+	"go.uber.org/fx"
+)
+
+// Backend registers a track source that can be selected with -source.
+type Backend struct {
+	Name         interfaces.Source
+	Capabilities []interfaces.Capability
+	New          func(params LibraryParams) (LibraryResult, error)
+}
+
+type BackendResult struct {
+	fx.Out
+	Backend *Backend `group:"client_backends"`
+}
+
+func (b *Backend) Supports(capability interfaces.Capability) bool {
+	return slices.Contains(b.Capabilities, capability)
+}
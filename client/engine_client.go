@@ -0,0 +1,353 @@
+package client
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/xdave/keyid/args"
+	"github.com/xdave/keyid/interfaces"
+	"github.com/xdave/keyid/models"
+	"github.com/xdave/keyid/util"
+
+	"go.uber.org/fx"
+)
+
+type engineTrack struct {
+	ID          int64
+	Path        string
+	Title       sql.NullString
+	Artist      sql.NullString
+	BPM         sql.NullFloat64
+	BPMAnalyzed sql.NullFloat64
+	Key         sql.NullInt64
+	Comment     sql.NullString
+	DateAdded   sql.NullInt64
+}
+
+type enginePlaylist struct {
+	ID           int64
+	Title        string
+	ParentListID int64
+	NextListID   int64
+}
+
+type engineEntity struct {
+	ID           int64
+	TrackID      int64
+	NextEntityID int64
+}
+
+// EngineClient reads tracks and playlists from an Engine DJ "Engine Library".
+type EngineClient struct {
+	args         *args.Args
+	libraryDir   string
+	databasePath string
+	db           *sql.DB
+	tracks       []interfaces.Item
+	byID         map[int64]interfaces.Item
+	byPath       map[string]interfaces.Item
+}
+
+var engineBackend = &Backend{
+	Name: interfaces.SourceEngine,
+	Capabilities: []interfaces.Capability{
+		interfaces.CapabilityNowPlaying,
+		interfaces.CapabilityPlaylistTree,
+	},
+	New: NewEngineClient,
+}
+
+func NewEngineBackend() BackendResult {
+	return BackendResult{Backend: engineBackend}
+}
+
+func NewEngineClient(params LibraryParams) (LibraryResult, error) {
+	libraryDir := params.Args.Engine
+	if libraryDir == "" {
+		var err error
+		if libraryDir, err = homePath("Music", "Engine Library"); err != nil {
+			return LibraryResult{}, err
+		}
+	}
+
+	databasePath := filepath.Join(libraryDir, "Database2", "m.db")
+	db, err := openReadOnlyDatabase(databasePath)
+	if err != nil {
+		return LibraryResult{}, err
+	}
+
+	engineClient := &EngineClient{
+		args:         params.Args,
+		libraryDir:   libraryDir,
+		databasePath: databasePath,
+		db:           db,
+		byID:         make(map[int64]interfaces.Item),
+		byPath:       make(map[string]interfaces.Item),
+	}
+
+	if err := engineClient.loadTracks(); err != nil {
+		db.Close()
+		return LibraryResult{}, err
+	}
+
+	params.Lifecycle.Append(fx.Hook{
+		OnStop: func(ctx context.Context) error {
+			return engineClient.Close()
+		},
+	})
+
+	return LibraryResult{
+		Library: engineClient,
+	}, nil
+}
+
+func (c *EngineClient) loadTracks() error {
+	rows, err := c.db.QueryContext(context.Background(),
+		`SELECT id, path, title, artist, bpm, bpmAnalyzed, key, comment, dateAdded FROM Track WHERE path IS NOT NULL`)
+	if err != nil {
+		return databaseError(c.databasePath, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		row := &engineTrack{}
+		err := rows.Scan(&row.ID, &row.Path, &row.Title, &row.Artist, &row.BPM, &row.BPMAnalyzed, &row.Key, &row.Comment, &row.DateAdded)
+		if err != nil {
+			return err
+		}
+		track := NewTrackFromEngine(row, c.libraryDir)
+		c.tracks = append(c.tracks, track)
+		c.byID[row.ID] = track
+		c.byPath[track.GetPath()] = track
+	}
+	return rows.Err()
+}
+
+// engineKeyToCamelot converts Engine's key index, which walks the circle of
+// fifths alternating major and relative minor starting at C major (0), Am (1).
+func engineKeyToCamelot(key int64) string {
+	kind := interfaces.Major
+	if key%2 == 1 {
+		kind = interfaces.Minor
+	}
+	return fmt.Sprintf("%d%s", interfaces.ModCyclic(int(key/2)+8, 12), kind)
+}
+
+func NewTrackFromEngine(row *engineTrack, libraryDir string) interfaces.Item {
+	bpm := row.BPMAnalyzed.Float64
+	if !row.BPMAnalyzed.Valid || bpm == 0 {
+		bpm = row.BPM.Float64
+	}
+
+	scaleName := ""
+	if row.Key.Valid && row.Key.Int64 >= 0 && row.Key.Int64 < 24 {
+		scaleName = engineKeyToCamelot(row.Key.Int64)
+	}
+
+	artistName := row.Artist.String
+	if artistName == "" {
+		artistName = "<none>"
+	}
+
+	var dateAdded string
+	if row.DateAdded.Valid {
+		dateAdded = time.Unix(row.DateAdded.Int64, 0).Format(time.DateOnly)
+	}
+
+	return &Track{
+		ID:        fmt.Sprint(row.ID),
+		BPM:       bpm,
+		Scale:     models.NewKey(scaleName),
+		Artist:    artistName,
+		Title:     row.Title.String,
+		Energy:    util.ParseEnergy(row.Comment.String),
+		Path:      filepath.Clean(filepath.Join(libraryDir, filepath.FromSlash(row.Path))),
+		DateAdded: dateAdded,
+		Tags:      []string{},
+	}
+}
+
+func (c *EngineClient) LoadPlaylist(ctx context.Context, name string) (interfaces.Collection, error) {
+	tracks := []interfaces.Item{}
+
+	if name == "" {
+		tracks = append(tracks, c.tracks...)
+	} else {
+		var playlistID int64
+		err := c.db.QueryRowContext(ctx, `SELECT id FROM Playlist WHERE title = ? LIMIT 1`, name).Scan(&playlistID)
+		if err == sql.ErrNoRows {
+			return nil, &interfaces.PlaylistNotFoundError{Name: name}
+		}
+		if err != nil {
+			return nil, databaseError(c.databasePath, err)
+		}
+		entities, err := c.playlistEntities(ctx, playlistID)
+		if err != nil {
+			return nil, err
+		}
+		for _, entity := range entities {
+			if track, ok := c.byID[entity.TrackID]; ok {
+				tracks = append(tracks, track)
+			}
+		}
+	}
+
+	return models.NewInMemoryCollection(tracks...).Filter(func(i interfaces.Item) bool {
+		return strings.Compare(i.GetDateAdded(), c.args.From) > 0
+	}), nil
+}
+
+// playlistEntities returns the entries of a playlist in play order. Engine
+// stores playlists as linked lists: each entity points to the next one with
+// nextEntityId, and the head is the entity nothing points to.
+func (c *EngineClient) playlistEntities(ctx context.Context, playlistID int64) ([]*engineEntity, error) {
+	rows, err := c.db.QueryContext(ctx,
+		`SELECT id, trackId, nextEntityId FROM PlaylistEntity WHERE listId = ? ORDER BY id`, playlistID)
+	if err != nil {
+		return nil, databaseError(c.databasePath, err)
+	}
+	defer rows.Close()
+
+	entities := []*engineEntity{}
+	for rows.Next() {
+		entity := &engineEntity{}
+		if err := rows.Scan(&entity.ID, &entity.TrackID, &entity.NextEntityID); err != nil {
+			return nil, err
+		}
+		entities = append(entities, entity)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	ordered, ok := linkedOrder(entities, func(entity *engineEntity) (int64, int64) {
+		return entity.ID, entity.NextEntityID
+	})
+	if !ok {
+		// Broken chain, fall back to the order the entities were added in
+		return entities, nil
+	}
+	return ordered, nil
+}
+
+// linkedOrder follows the links of items, which are their ID and the ID of
+// the next one, from the item nothing links to. It returns false unless that
+// goes through every item once and ends on a link to no item.
+func linkedOrder[T any](items []T, link func(item T) (int64, int64)) ([]T, bool) {
+	byID := make(map[int64]T)
+	referenced := make(map[int64]bool)
+	for _, item := range items {
+		id, next := link(item)
+		byID[id] = item
+		referenced[next] = true
+	}
+
+	ordered := []T{}
+	for _, head := range items {
+		if id, _ := link(head); referenced[id] {
+			continue
+		}
+		for item, ok := head, true; ok && len(ordered) <= len(items); {
+			ordered = append(ordered, item)
+			_, next := link(item)
+			item, ok = byID[next]
+		}
+		break
+	}
+	return ordered, len(ordered) == len(items) && len(byID) == len(items)
+}
+
+func (c *EngineClient) GetPlaylists(ctx context.Context) ([]*interfaces.PlaylistNode, error) {
+	rows, err := c.db.QueryContext(ctx,
+		`SELECT id, title, parentListId, nextListId FROM Playlist`)
+	if err != nil {
+		return nil, databaseError(c.databasePath, err)
+	}
+	defer rows.Close()
+
+	playlists := []*enginePlaylist{}
+	for rows.Next() {
+		playlist := &enginePlaylist{}
+		if err := rows.Scan(&playlist.ID, &playlist.Title, &playlist.ParentListID, &playlist.NextListID); err != nil {
+			return nil, err
+		}
+		playlists = append(playlists, playlist)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Siblings are linked with nextListId, like playlist entities
+	children := make(map[int64][]*enginePlaylist)
+	for _, playlist := range playlists {
+		children[playlist.ParentListID] = append(children[playlist.ParentListID], playlist)
+	}
+
+	var build func(parentID int64) []*interfaces.PlaylistNode
+	build = func(parentID int64) []*interfaces.PlaylistNode {
+		siblings := children[parentID]
+		ordered, ok := linkedOrder(siblings, func(playlist *enginePlaylist) (int64, int64) {
+			return playlist.ID, playlist.NextListID
+		})
+		if !ok {
+			ordered = siblings
+		}
+
+		nodes := []*interfaces.PlaylistNode{}
+		for _, playlist := range ordered {
+			nodes = append(nodes, &interfaces.PlaylistNode{
+				ID:       fmt.Sprint(playlist.ID),
+				Name:     playlist.Title,
+				Children: build(playlist.ID),
+			})
+		}
+		return nodes
+	}
+
+	return build(0), nil
+}
+
+// GetNowPlaying falls back to the most recent entry in Engine's history
+// database (hm.db), matching it to the library by file path.
+func (c *EngineClient) GetNowPlaying(ctx context.Context) (interfaces.Item, error) {
+	historyPath := filepath.Join(c.libraryDir, "Database2", "hm.db")
+	if _, err := os.Stat(historyPath); os.IsNotExist(err) {
+		// Nothing has been played yet
+		return nil, nil
+	}
+	history, err := openReadOnlyDatabase(historyPath)
+	if err != nil {
+		return nil, err
+	}
+	defer history.Close()
+
+	var path string
+	err = history.QueryRowContext(ctx,
+		`SELECT t.path FROM HistorylistEntity h JOIN Track t ON t.id = h.trackId ORDER BY h.startTime DESC LIMIT 1`).Scan(&path)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, databaseError(historyPath, err)
+	}
+
+	path = filepath.Clean(filepath.Join(c.libraryDir, filepath.FromSlash(path)))
+	track, ok := c.byPath[path]
+	if !ok {
+		return nil, &interfaces.TrackNotFoundError{Pattern: path}
+	}
+	return track, nil
+}
+
+func (c *EngineClient) Supports(capability interfaces.Capability) bool {
+	return engineBackend.Supports(capability)
+}
+
+func (c *EngineClient) Close() error {
+	return c.db.Close()
+}
@@ -0,0 +1,185 @@
+package client
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/xdave/keyid/args"
+	"github.com/xdave/keyid/interfaces"
+	"github.com/xdave/keyid/models"
+
+	"go.uber.org/fx/fxtest"
+)
+
+// newSQLiteFixture creates a plain SQLite database at path with the given
+// statements run in it.
+func newSQLiteFixture(t testing.TB, path string, statements ...string) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for _, statement := range statements {
+		if _, err := db.Exec(statement); err != nil {
+			t.Fatalf("%s: %v", statement, err)
+		}
+	}
+}
+
+func trackIDs(c interfaces.Collection) []string {
+	ids := []string{}
+	for _, item := range c.Items() {
+		ids = append(ids, item.GetID())
+	}
+	return ids
+}
+
+// newEngineFixture generates an Engine Library whose playlists are linked
+// lists stored out of order, and opens it the way the engine source does.
+func newEngineFixture(t *testing.T) *EngineClient {
+	libraryDir := t.TempDir()
+	newSQLiteFixture(t, filepath.Join(libraryDir, "Database2", "m.db"),
+		`CREATE TABLE Track (id INTEGER PRIMARY KEY, path TEXT, title TEXT, artist TEXT, bpm REAL, bpmAnalyzed REAL, key INTEGER, comment TEXT, dateAdded INTEGER)`,
+		`INSERT INTO Track VALUES
+			(10, '../Music/a.mp3', 'A', 'Artist', 120, 120.5, 0, 'Energy 6', 1700000000),
+			(20, '../Music/b.mp3', 'B', NULL, 121, NULL, 1, NULL, 1700000000),
+			(30, '../Music/c.mp3', 'C', 'Artist', 122, 0, 23, NULL, 1700000000),
+			(40, '../Music/d.mp3', 'D', 'Artist', 123, 123, NULL, NULL, 1700000000),
+			(50, NULL, 'No file', 'Artist', 124, 124, 2, NULL, 1700000000)`,
+		`CREATE TABLE Playlist (id INTEGER PRIMARY KEY, title TEXT, parentListId INTEGER, nextListId INTEGER)`,
+		// Folder > [Set, Dangling], Cycle, Loop, stored out of order
+		`INSERT INTO Playlist VALUES
+			(5, 'Loop', 0, 0),
+			(4, 'Cycle', 0, 5),
+			(2, 'Set', 1, 3),
+			(3, 'Dangling', 1, 0),
+			(1, 'Folder', 0, 4)`,
+		`CREATE TABLE PlaylistEntity (id INTEGER PRIMARY KEY, listId INTEGER, trackId INTEGER, nextEntityId INTEGER)`,
+		// Set plays 30, 10, 40, 20
+		`INSERT INTO PlaylistEntity VALUES
+			(7, 2, 20, 0),
+			(5, 2, 10, 6),
+			(8, 2, 30, 5),
+			(6, 2, 40, 7)`,
+		// Dangling points to an entity that is gone, Cycle loops back after its
+		// head and Loop links an entity to itself
+		`INSERT INTO PlaylistEntity VALUES
+			(13, 3, 30, 11),
+			(11, 3, 10, 99),
+			(12, 3, 20, 0),
+			(22, 4, 20, 21),
+			(21, 4, 10, 22),
+			(23, 4, 99, 22),
+			(31, 5, 10, 32),
+			(32, 5, 20, 32),
+			(33, 5, 30, 0)`,
+	)
+
+	result, err := NewEngineClient(LibraryParams{
+		Lifecycle: fxtest.NewLifecycle(t),
+		Args:      &args.Args{Engine: libraryDir, From: "1970-01-01"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { result.Library.Close() })
+	return result.Library.(*EngineClient)
+}
+
+func TestEnginePlaylistOrder(t *testing.T) {
+	client := newEngineFixture(t)
+	ctx := context.Background()
+	tests := []struct {
+		playlist string
+		want     []string
+	}{
+		{"", []string{"10", "20", "30", "40"}},
+		{"Set", []string{"30", "10", "40", "20"}},
+		// Broken chains keep the order the entities were added in
+		{"Dangling", []string{"10", "20", "30"}},
+		{"Cycle", []string{"10", "20"}},
+		{"Loop", []string{"10", "20", "30"}},
+	}
+	for _, tt := range tests {
+		collection, err := client.LoadPlaylist(ctx, tt.playlist)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := trackIDs(collection); !slices.Equal(got, tt.want) {
+			t.Errorf("LoadPlaylist(%q) = %v, want %v", tt.playlist, got, tt.want)
+		}
+	}
+
+	var notFound *interfaces.PlaylistNotFoundError
+	if _, err := client.LoadPlaylist(ctx, "Missing"); !errors.As(err, &notFound) {
+		t.Errorf("LoadPlaylist(\"Missing\") error = %v, want a PlaylistNotFoundError", err)
+	}
+}
+
+func TestEnginePlaylistTree(t *testing.T) {
+	playlists, err := newEngineFixture(t).GetPlaylists(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	var walk func(nodes []*interfaces.PlaylistNode, prefix string)
+	walk = func(nodes []*interfaces.PlaylistNode, prefix string) {
+		for _, node := range nodes {
+			names = append(names, prefix+node.Name)
+			walk(node.Children, prefix+node.Name+"/")
+		}
+	}
+	walk(playlists, "")
+	want := []string{"Folder", "Folder/Set", "Folder/Dangling", "Cycle", "Loop"}
+	if !slices.Equal(names, want) {
+		t.Errorf("GetPlaylists() = %v, want %v", names, want)
+	}
+}
+
+// Engine's key walks the circle of fifths from C major, alternating major and
+// relative minor.
+func TestEngineTracks(t *testing.T) {
+	client := newEngineFixture(t)
+	tests := []struct {
+		id     int64
+		key    string
+		bpm    float64
+		artist string
+		energy int
+	}{
+		{10, "8B", 120.5, "Artist", 6},
+		{20, "8A", 121, "<none>", 0},
+		{30, "7A", 122, "Artist", 0},
+		{40, "?", 123, "Artist", 0},
+	}
+	for _, tt := range tests {
+		track := client.byID[tt.id]
+		if track == nil {
+			t.Errorf("track %d is missing", tt.id)
+			continue
+		}
+		if track.GetScale().String() != tt.key || track.GetBPM() != tt.bpm || track.GetArtist() != tt.artist || track.GetEnergy() != tt.energy {
+			t.Errorf("track %d = %v %v %q %v, want %v %v %q %v", tt.id, track.GetScale(), track.GetBPM(), track.GetArtist(), track.GetEnergy(), tt.key, tt.bpm, tt.artist, tt.energy)
+		}
+	}
+	if path := client.byID[10].GetPath(); path != filepath.Join(filepath.Dir(client.libraryDir), "Music", "a.mp3") {
+		t.Errorf("path = %s, want it relative to the library", path)
+	}
+	if len(client.tracks) != 4 {
+		t.Errorf("loaded %d tracks, want the 4 with a path", len(client.tracks))
+	}
+	for key, name := range []string{"C", "Am", "G", "Em", "D", "Bm", "A", "F#m", "E", "C#m", "B", "G#m",
+		"F#", "D#m", "Db", "Bbm", "Ab", "Fm", "Eb", "Cm", "Bb", "Gm", "F", "Dm"} {
+		if got, want := engineKeyToCamelot(int64(key)), models.NewKey(name).String(); got != want {
+			t.Errorf("engineKeyToCamelot(%d) = %s, want %s (%s)", key, got, want, name)
+		}
+	}
+}
@@ -0,0 +1,244 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/xdave/keyid/args"
+	"github.com/xdave/keyid/interfaces"
+	"github.com/xdave/keyid/models"
+	"github.com/xdave/keyid/tags"
+	"github.com/xdave/keyid/util"
+)
+
+// FolderClient scans a music folder and reads key, BPM and comments from the
+// tags embedded in each file. Every sub folder is used as a playlist.
+type FolderClient struct {
+	args   *args.Args
+	root   string
+	tracks []interfaces.Item
+	byDir  map[string][]interfaces.Item
+}
+
+var folderBackend = &Backend{
+	Name: interfaces.SourceFolder,
+	Capabilities: []interfaces.Capability{
+		interfaces.CapabilityPlaylistTree,
+	},
+	New: NewFolderClient,
+}
+
+func NewFolderBackend() BackendResult {
+	return BackendResult{Backend: folderBackend}
+}
+
+func NewFolderClient(params LibraryParams) (LibraryResult, error) {
+	root := params.Args.Folder
+	if root == "" {
+		var err error
+		if root, err = homePath("Music"); err != nil {
+			return LibraryResult{}, err
+		}
+	}
+
+	folderClient := &FolderClient{
+		args:  params.Args,
+		root:  root,
+		byDir: make(map[string][]interfaces.Item),
+	}
+
+	// Reading the tags of every file is the slow part, so files keep their
+	// cached tags until their size or modification time changes
+	location, _ := filepath.Abs(root)
+	cache := openTrackCache(params.Args, interfaces.SourceFolder, location)
+	seen := make(map[string]bool)
+
+	err := filepath.WalkDir(folderClient.root, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if filePath == folderClient.root {
+				return err
+			}
+			// One unreadable folder or file shouldn't lose the rest
+			log.Default().Printf("skipping '%s': %v", filePath, err)
+			if entry != nil && entry.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if entry.IsDir() || !tags.IsSupported(filePath) {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil
+		}
+
+		version := fmt.Sprintf("%d/%d", info.ModTime().UnixNano(), info.Size())
+		track, ok := cache.get(filePath, version)
+		if !ok {
+			track = NewTrackFromFile(filePath, info)
+			cache.put(filePath, version, track)
+		}
+		seen[filePath] = true
+
+		dir, _ := filepath.Rel(folderClient.root, filepath.Dir(filePath))
+		dir = filepath.ToSlash(dir)
+
+		folderClient.tracks = append(folderClient.tracks, track)
+		folderClient.byDir[dir] = append(folderClient.byDir[dir], track)
+		return nil
+	})
+	if err != nil {
+		return LibraryResult{}, err
+	}
+
+	cache.prune(func(id string) bool {
+		return seen[id]
+	})
+	if err := cache.save(); err != nil {
+		log.Default().Printf("cannot write track cache: %v", err)
+	}
+
+	return LibraryResult{
+		Library: folderClient,
+	}, nil
+}
+
+func NewTrackFromFile(filePath string, info fs.FileInfo) interfaces.Item {
+	fileTags, err := tags.Read(filePath)
+	if err != nil {
+		// Untagged files are still tracks, they just have no key
+		fileTags = &tags.Tags{}
+	}
+
+	title := fileTags.Title
+	if title == "" {
+		title = strings.TrimSuffix(info.Name(), filepath.Ext(info.Name()))
+	}
+
+	scaleName := fileTags.Key
+
+	artistName := fileTags.Artist
+	if artistName == "" {
+		artistName = "<none>"
+	}
+
+	return &Track{
+		ID:        filePath,
+		BPM:       fileTags.BPM,
+		Scale:     models.NewKey(scaleName),
+		Artist:    artistName,
+		Title:     title,
+		Energy:    util.ParseEnergy(fileTags.Comment),
+		Path:      filePath,
+		DateAdded: info.ModTime().Format(time.DateOnly),
+		Tags:      []string{},
+	}
+}
+
+// LoadPlaylist loads the tracks in a folder and all of its sub folders. The
+// name is either the folder's path relative to the music folder or just its
+// name.
+func (c *FolderClient) LoadPlaylist(ctx context.Context, name string) (interfaces.Collection, error) {
+	tracks := []interfaces.Item{}
+
+	if name == "" {
+		tracks = append(tracks, c.tracks...)
+	} else {
+		dir := c.findDir(name)
+		if dir == "" {
+			return nil, &interfaces.PlaylistNotFoundError{Name: name}
+		}
+		for _, subDir := range c.sortedDirs() {
+			if subDir == dir || strings.HasPrefix(subDir, dir+"/") {
+				tracks = append(tracks, c.byDir[subDir]...)
+			}
+		}
+	}
+
+	return models.NewInMemoryCollection(tracks...).Filter(func(i interfaces.Item) bool {
+		return strings.Compare(i.GetDateAdded(), c.args.From) > 0
+	}), nil
+}
+
+func (c *FolderClient) findDir(name string) string {
+	name = strings.Trim(filepath.ToSlash(name), "/")
+	dirs := c.playlistDirs()
+	for _, dir := range dirs {
+		if dir == name {
+			return dir
+		}
+	}
+	for _, dir := range dirs {
+		if path.Base(dir) == name {
+			return dir
+		}
+	}
+	return ""
+}
+
+func (c *FolderClient) sortedDirs() []string {
+	dirs := make([]string, 0, len(c.byDir))
+	for dir := range c.byDir {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	return dirs
+}
+
+// playlistDirs returns every folder holding tracks, including the folders
+// that only hold them in sub folders.
+func (c *FolderClient) playlistDirs() []string {
+	seen := make(map[string]bool)
+	dirs := []string{}
+	for _, dir := range c.sortedDirs() {
+		for ; dir != "." && dir != "/" && !seen[dir]; dir = path.Dir(dir) {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	sort.Strings(dirs)
+	return dirs
+}
+
+func (c *FolderClient) GetPlaylists(ctx context.Context) ([]*interfaces.PlaylistNode, error) {
+	playlistMap := make(map[string]*interfaces.PlaylistNode)
+	var roots []*interfaces.PlaylistNode
+
+	// Sorted, so parents are always created before their children
+	for _, dir := range c.playlistDirs() {
+		node := &interfaces.PlaylistNode{
+			ID:       dir,
+			Name:     path.Base(dir),
+			Children: []*interfaces.PlaylistNode{},
+		}
+		playlistMap[dir] = node
+		if parent, ok := playlistMap[path.Dir(dir)]; ok {
+			parent.Children = append(parent.Children, node)
+		} else {
+			roots = append(roots, node)
+		}
+	}
+
+	return roots, nil
+}
+
+// GetNowPlaying never knows the track, since a folder has no history.
+func (c *FolderClient) GetNowPlaying(ctx context.Context) (interfaces.Item, error) {
+	return nil, nil
+}
+
+func (c *FolderClient) Supports(capability interfaces.Capability) bool {
+	return folderBackend.Supports(capability)
+}
+
+func (c *FolderClient) Close() error {
+	return nil
+}
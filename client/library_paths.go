@@ -0,0 +1,28 @@
+package client
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// homePath joins elem onto the user's home directory, failing when it is
+// unknown, e.g. because $HOME isn't set.
+func homePath(elem ...string) (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(append([]string{homeDir}, elem...)...), nil
+}
+
+// defaultMixxxDatabase returns where Mixxx keeps mixxxdb.sqlite on this OS.
+func defaultMixxxDatabase() (string, error) {
+	switch runtime.GOOS {
+	case "darwin":
+		return homePath("Library", "Containers", "org.mixxx.mixxx", "Data", "Library", "Application Support", "Mixxx", "mixxxdb.sqlite")
+	case "windows":
+		return homePath("AppData", "Local", "Mixxx", "mixxxdb.sqlite")
+	}
+	return homePath(".mixxx", "mixxxdb.sqlite")
+}
@@ -0,0 +1,281 @@
+package client
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/xdave/keyid/args"
+	"github.com/xdave/keyid/interfaces"
+	"github.com/xdave/keyid/models"
+	"github.com/xdave/keyid/util"
+
+	"go.uber.org/fx"
+)
+
+const (
+	mixxxPlaylistNormal = 0
+	mixxxPlaylistSetLog = 2
+)
+
+type mixxxTrack struct {
+	ID            int64
+	Location      string
+	Title         sql.NullString
+	Artist        sql.NullString
+	BPM           sql.NullFloat64
+	Key           sql.NullString
+	KeyID         sql.NullInt64
+	Comment       sql.NullString
+	DateTimeAdded sql.NullString
+}
+
+// MixxxClient reads tracks, playlists and crates from Mixxx's mixxxdb.sqlite.
+type MixxxClient struct {
+	args         *args.Args
+	databasePath string
+	db           *sql.DB
+	tracks       []interfaces.Item
+	byID         map[int64]interfaces.Item
+}
+
+var mixxxBackend = &Backend{
+	Name: interfaces.SourceMixxx,
+	Capabilities: []interfaces.Capability{
+		interfaces.CapabilityNowPlaying,
+		interfaces.CapabilityPlaylistTree,
+	},
+	New: NewMixxxClient,
+}
+
+func NewMixxxBackend() BackendResult {
+	return BackendResult{Backend: mixxxBackend}
+}
+
+func NewMixxxClient(params LibraryParams) (LibraryResult, error) {
+	databasePath := params.Args.Mixxx
+	if databasePath == "" {
+		var err error
+		if databasePath, err = defaultMixxxDatabase(); err != nil {
+			return LibraryResult{}, err
+		}
+	}
+
+	db, err := openReadOnlyDatabase(databasePath)
+	if err != nil {
+		return LibraryResult{}, err
+	}
+
+	mixxxClient := &MixxxClient{
+		args:         params.Args,
+		databasePath: databasePath,
+		db:           db,
+		byID:         make(map[int64]interfaces.Item),
+	}
+
+	if err := mixxxClient.loadTracks(); err != nil {
+		db.Close()
+		return LibraryResult{}, err
+	}
+
+	params.Lifecycle.Append(fx.Hook{
+		OnStop: func(ctx context.Context) error {
+			return mixxxClient.Close()
+		},
+	})
+
+	return LibraryResult{
+		Library: mixxxClient,
+	}, nil
+}
+
+func (c *MixxxClient) loadTracks() error {
+	rows, err := c.db.QueryContext(context.Background(), `
+		SELECT l.id, tl.location, l.title, l.artist, l.bpm, l.key, l.key_id, l.comment, l.datetime_added
+		FROM library l JOIN track_locations tl ON tl.id = l.location
+		WHERE l.mixxx_deleted = 0 AND tl.fs_deleted = 0`)
+	if err != nil {
+		return databaseError(c.databasePath, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		row := &mixxxTrack{}
+		err := rows.Scan(&row.ID, &row.Location, &row.Title, &row.Artist, &row.BPM, &row.Key, &row.KeyID, &row.Comment, &row.DateTimeAdded)
+		if err != nil {
+			return err
+		}
+		track := NewTrackFromMixxx(row)
+		c.tracks = append(c.tracks, track)
+		c.byID[row.ID] = track
+	}
+	return rows.Err()
+}
+
+func NewTrackFromMixxx(row *mixxxTrack) interfaces.Item {
+	scaleName := row.Key.String
+	// Mixxx's key_id is Traktor's MUSICAL_KEY shifted by one, 0 means no key
+	if camelot, ok := models.TraktorKeyToCamelot[int(row.KeyID.Int64)-1]; row.KeyID.Valid && ok {
+		scaleName = camelot
+	}
+
+	artistName := row.Artist.String
+	if artistName == "" {
+		artistName = "<none>"
+	}
+
+	// datetime_added is an ISO 8601 timestamp, keep the date part
+	dateAdded := row.DateTimeAdded.String
+	if len(dateAdded) > 10 {
+		dateAdded = dateAdded[:10]
+	}
+
+	return &Track{
+		ID:        fmt.Sprint(row.ID),
+		BPM:       row.BPM.Float64,
+		Scale:     models.NewKey(scaleName),
+		Artist:    artistName,
+		Title:     row.Title.String,
+		Energy:    util.ParseEnergy(row.Comment.String),
+		Path:      row.Location,
+		DateAdded: dateAdded,
+		Tags:      []string{},
+	}
+}
+
+func (c *MixxxClient) LoadPlaylist(ctx context.Context, name string) (interfaces.Collection, error) {
+	tracks := []interfaces.Item{}
+
+	if name == "" {
+		tracks = append(tracks, c.tracks...)
+	} else {
+		trackIDs, err := c.playlistTrackIDs(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		if trackIDs == nil {
+			return nil, &interfaces.PlaylistNotFoundError{Name: name}
+		}
+		for _, id := range trackIDs {
+			if track, ok := c.byID[id]; ok {
+				tracks = append(tracks, track)
+			}
+		}
+	}
+
+	return models.NewInMemoryCollection(tracks...).Filter(func(i interfaces.Item) bool {
+		return strings.Compare(i.GetDateAdded(), c.args.From) > 0
+	}), nil
+}
+
+// playlistTrackIDs looks the name up in the playlists first and the crates
+// second, returning nil when neither exists.
+func (c *MixxxClient) playlistTrackIDs(ctx context.Context, name string) ([]int64, error) {
+	var id int64
+	err := c.db.QueryRowContext(ctx,
+		`SELECT id FROM Playlists WHERE name = ? AND hidden = ? LIMIT 1`, name, mixxxPlaylistNormal).Scan(&id)
+	if err == nil {
+		return c.queryTrackIDs(ctx, `SELECT track_id FROM PlaylistTracks WHERE playlist_id = ? ORDER BY position`, id)
+	}
+	if err != sql.ErrNoRows {
+		return nil, databaseError(c.databasePath, err)
+	}
+
+	err = c.db.QueryRowContext(ctx, `SELECT id FROM crates WHERE name = ? LIMIT 1`, name).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, databaseError(c.databasePath, err)
+	}
+	return c.queryTrackIDs(ctx, `SELECT track_id FROM crate_tracks WHERE crate_id = ?`, id)
+}
+
+func (c *MixxxClient) queryTrackIDs(ctx context.Context, query string, args ...any) ([]int64, error) {
+	rows, err := c.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, databaseError(c.databasePath, err)
+	}
+	defer rows.Close()
+
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// GetPlaylists returns Mixxx's playlists and crates under two folders, since
+// Mixxx has no folders of its own.
+func (c *MixxxClient) GetPlaylists(ctx context.Context) ([]*interfaces.PlaylistNode, error) {
+	playlists := &interfaces.PlaylistNode{ID: "playlists", Name: "Playlists", Children: []*interfaces.PlaylistNode{}}
+	crates := &interfaces.PlaylistNode{ID: "crates", Name: "Crates", Children: []*interfaces.PlaylistNode{}}
+
+	err := c.queryPlaylistNodes(ctx, playlists, "playlist",
+		`SELECT id, name FROM Playlists WHERE hidden = ? ORDER BY position`, mixxxPlaylistNormal)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.queryPlaylistNodes(ctx, crates, "crate", `SELECT id, name FROM crates ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+
+	return []*interfaces.PlaylistNode{playlists, crates}, nil
+}
+
+// queryPlaylistNodes adds a child to the folder for every "id, name" row,
+// prefixing the IDs since playlists and crates are numbered separately.
+func (c *MixxxClient) queryPlaylistNodes(ctx context.Context, folder *interfaces.PlaylistNode, prefix string, query string, args ...any) error {
+	rows, err := c.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return databaseError(c.databasePath, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return err
+		}
+		folder.Children = append(folder.Children, &interfaces.PlaylistNode{ID: fmt.Sprintf("%s-%d", prefix, id), Name: name})
+	}
+	return rows.Err()
+}
+
+// GetNowPlaying uses the last track of the newest set log ("played") playlist
+// Mixxx records history in.
+func (c *MixxxClient) GetNowPlaying(ctx context.Context) (interfaces.Item, error) {
+	var trackID int64
+	err := c.db.QueryRowContext(ctx, `
+		SELECT pt.track_id FROM PlaylistTracks pt
+		WHERE pt.playlist_id = (SELECT id FROM Playlists WHERE hidden = ? ORDER BY date_created DESC, id DESC LIMIT 1)
+		ORDER BY pt.position DESC LIMIT 1`, mixxxPlaylistSetLog).Scan(&trackID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, databaseError(c.databasePath, err)
+	}
+
+	track, ok := c.byID[trackID]
+	if !ok {
+		return nil, &interfaces.TrackNotFoundError{Pattern: fmt.Sprint(trackID)}
+	}
+	return track, nil
+}
+
+func (c *MixxxClient) Supports(capability interfaces.Capability) bool {
+	return mixxxBackend.Supports(capability)
+}
+
+func (c *MixxxClient) Close() error {
+	return c.db.Close()
+}
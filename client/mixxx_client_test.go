@@ -0,0 +1,165 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/xdave/keyid/args"
+	"github.com/xdave/keyid/interfaces"
+	"github.com/xdave/keyid/models"
+
+	"go.uber.org/fx/fxtest"
+)
+
+// mixxxKeys are Mixxx's ChromaticKey values from 1, 0 being no key.
+var mixxxKeys = []string{"C", "Db", "D", "Eb", "E", "F", "F#", "G", "Ab", "A", "Bb", "B",
+	"Cm", "C#m", "Dm", "Ebm", "Em", "Fm", "F#m", "Gm", "G#m", "Am", "Bbm", "Bm"}
+
+// newMixxxFixture generates a mixxxdb.sqlite with a track for every key_id
+// and opens it the way the mixxx source does.
+func newMixxxFixture(t *testing.T, statements ...string) *MixxxClient {
+	tracks := []string{}
+	locations := []string{}
+	for keyID := 1; keyID <= len(mixxxKeys); keyID++ {
+		tracks = append(tracks, fmt.Sprintf("(%d, %d, 'Key %d', 'Artist', 124, '1d', %d, 'Energy 6', '2024-01-31T12:00:00Z', 0)", keyID, keyID, keyID, keyID))
+		locations = append(locations, fmt.Sprintf("(%d, '/Music/%d.mp3', 0)", keyID, keyID))
+	}
+	databasePath := filepath.Join(t.TempDir(), "mixxxdb.sqlite")
+	newSQLiteFixture(t, databasePath, append([]string{
+		`CREATE TABLE track_locations (id INTEGER PRIMARY KEY, location TEXT, fs_deleted INTEGER)`,
+		`CREATE TABLE library (id INTEGER PRIMARY KEY, location INTEGER, title TEXT, artist TEXT, bpm REAL, key TEXT, key_id INTEGER, comment TEXT, datetime_added TEXT, mixxx_deleted INTEGER)`,
+		`INSERT INTO track_locations VALUES ` + strings.Join(locations, ", "),
+		`INSERT INTO library VALUES ` + strings.Join(tracks, ", "),
+		// The key text is only read without a key_id
+		`INSERT INTO track_locations VALUES (101, '/Music/none.mp3', 0), (102, '/Music/text.mp3', 0), (103, '/Music/bad.mp3', 0),
+			(104, '/Music/hidden.mp3', 0), (105, '/Music/gone.mp3', 1)`,
+		`INSERT INTO library VALUES
+			(101, 101, 'None', NULL, 120, NULL, 0, NULL, NULL, 0),
+			(102, 102, 'Text', 'Artist', 120, 'Am', NULL, NULL, '2024-01-31T12:00:00Z', 0),
+			(103, 103, 'Bad', 'Artist', 120, '9A', 25, NULL, '2024-01-31T12:00:00Z', 0),
+			(104, 104, 'Hidden', 'Artist', 120, NULL, 1, NULL, '2024-01-31T12:00:00Z', 1),
+			(105, 105, 'Gone', 'Artist', 120, NULL, 1, NULL, '2024-01-31T12:00:00Z', 0)`,
+		`CREATE TABLE Playlists (id INTEGER PRIMARY KEY, name TEXT, position INTEGER, hidden INTEGER, date_created TEXT)`,
+		`CREATE TABLE PlaylistTracks (id INTEGER PRIMARY KEY, playlist_id INTEGER, track_id INTEGER, position INTEGER)`,
+		`CREATE TABLE crates (id INTEGER PRIMARY KEY, name TEXT)`,
+		`CREATE TABLE crate_tracks (crate_id INTEGER, track_id INTEGER)`,
+		`INSERT INTO Playlists VALUES (1, 'Friday', 2, 0, '2024-01-01'), (2, 'Auto DJ', 1, 1, '2024-01-01'), (3, 'Warmup', 1, 0, '2024-01-01')`,
+		`INSERT INTO PlaylistTracks (playlist_id, track_id, position) VALUES (1, 22, 3), (1, 1, 1), (1, 105, 2), (1, 102, 4), (3, 5, 1)`,
+		`INSERT INTO crates VALUES (1, 'Techno'), (2, 'Deep')`,
+		`INSERT INTO crate_tracks VALUES (1, 3), (2, 4), (2, 6)`,
+	}, statements...)...)
+
+	result, err := NewMixxxClient(LibraryParams{
+		Lifecycle: fxtest.NewLifecycle(t),
+		Args:      &args.Args{Mixxx: databasePath, From: "1970-01-01"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { result.Library.Close() })
+	return result.Library.(*MixxxClient)
+}
+
+// key_id is Traktor's MUSICAL_KEY plus one.
+func TestMixxxKeyID(t *testing.T) {
+	client := newMixxxFixture(t)
+	for i, name := range mixxxKeys {
+		keyID := int64(i + 1)
+		if got, want := client.byID[keyID].GetScale(), models.NewKey(name); !got.IsEqual(want) {
+			t.Errorf("key_id %d = %v, want %v (%s)", keyID, got, want, name)
+		}
+	}
+	tests := []struct {
+		id   int64
+		want string
+	}{
+		{101, "?"},
+		{102, "8A"},
+		{103, "9A"},
+	}
+	for _, tt := range tests {
+		if got := client.byID[tt.id].GetScale().String(); got != tt.want {
+			t.Errorf("track %d key = %s, want %s", tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestMixxxTracks(t *testing.T) {
+	client := newMixxxFixture(t)
+	if len(client.tracks) != len(mixxxKeys)+3 {
+		t.Errorf("loaded %d tracks, want all but the deleted ones", len(client.tracks))
+	}
+	track := client.byID[1].(*Track)
+	if track.Path != "/Music/1.mp3" || track.BPM != 124 || track.Energy != 6 || track.DateAdded != "2024-01-31" {
+		t.Errorf("track = %+v", track)
+	}
+	if artist := client.byID[101].GetArtist(); artist != "<none>" {
+		t.Errorf("artist = %q, want <none>", artist)
+	}
+}
+
+// Playlists are in position order and come before crates of the same name.
+func TestMixxxPlaylists(t *testing.T) {
+	client := newMixxxFixture(t)
+	ctx := context.Background()
+	tests := []struct {
+		playlist string
+		want     []string
+	}{
+		{"Friday", []string{"1", "22", "102"}},
+		{"Deep", []string{"4", "6"}},
+		{"Techno", []string{"3"}},
+	}
+	for _, tt := range tests {
+		collection, err := client.LoadPlaylist(ctx, tt.playlist)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := trackIDs(collection); !slices.Equal(got, tt.want) {
+			t.Errorf("LoadPlaylist(%q) = %v, want %v", tt.playlist, got, tt.want)
+		}
+	}
+	var notFound *interfaces.PlaylistNotFoundError
+	for _, name := range []string{"Auto DJ", "Missing"} {
+		if _, err := client.LoadPlaylist(ctx, name); !errors.As(err, &notFound) {
+			t.Errorf("LoadPlaylist(%q) error = %v, want a PlaylistNotFoundError", name, err)
+		}
+	}
+
+	playlists, err := client.GetPlaylists(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, folder := range playlists {
+		for _, node := range folder.Children {
+			got = append(got, folder.Name+"/"+node.Name+" "+node.ID)
+		}
+	}
+	want := []string{"Playlists/Warmup playlist-3", "Playlists/Friday playlist-1", "Crates/Deep crate-2", "Crates/Techno crate-1"}
+	if !slices.Equal(got, want) {
+		t.Errorf("GetPlaylists() = %v, want %v", got, want)
+	}
+}
+
+// Now playing is the last track of the newest set log.
+func TestMixxxNowPlaying(t *testing.T) {
+	ctx := context.Background()
+	if track, err := newMixxxFixture(t).GetNowPlaying(ctx); track != nil || err != nil {
+		t.Errorf("GetNowPlaying() without a set log = %v, %v", track, err)
+	}
+
+	client := newMixxxFixture(t,
+		`INSERT INTO Playlists VALUES (10, '2024-01-30', 0, 2, '2024-01-30'), (11, '2024-01-31', 0, 2, '2024-01-31')`,
+		`INSERT INTO PlaylistTracks (playlist_id, track_id, position) VALUES (10, 7, 1), (11, 9, 2), (11, 8, 1)`,
+	)
+	track, err := client.GetNowPlaying(ctx)
+	if err != nil || track == nil || track.GetID() != "9" {
+		t.Errorf("GetNowPlaying() = %v, %v, want track 9", track, err)
+	}
+}
@@ -5,7 +5,13 @@ import "go.uber.org/fx"
 var Module = fx.Module("client",
 	fx.Provide(
 		NewRekordboxOptionsResolver,
-		NewRekordboxHistory,
-		NewRekordboxClient,
+		NewRekordboxBackend,
+		NewRekordboxXmlBackend,
+		NewTraktorBackend,
+		NewSeratoBackend,
+		NewEngineBackend,
+		NewMixxxBackend,
+		NewFolderBackend,
+		ProvideLibrary,
 	),
 )
@@ -0,0 +1,43 @@
+package client
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/xdave/keyid/args"
+	"github.com/xdave/keyid/interfaces"
+
+	"go.uber.org/fx"
+)
+
+type LibraryParams struct {
+	fx.In
+	fx.Lifecycle
+	OptionsResolver *RekordboxOptionsResolver
+	Args            *args.Args
+	Backends        []*Backend `group:"client_backends"`
+}
+
+type LibraryResult struct {
+	fx.Out
+	Library interfaces.Library
+}
+
+func ProvideLibrary(params LibraryParams) (LibraryResult, error) {
+	names := []string{}
+	for _, backend := range params.Backends {
+		if backend.Name != params.Args.Source {
+			names = append(names, backend.Name)
+			continue
+		}
+		if !backend.Supports(interfaces.CapabilityTags) && (params.Args.Tags != "" || params.Args.ExcludeTags != "") {
+			fmt.Fprintf(os.Stderr, "Warning: source '%s' has no tags, ignoring -tags and -excludeTags\n", backend.Name)
+		}
+		return backend.New(params)
+	}
+
+	sort.Strings(names)
+	return LibraryResult{}, fmt.Errorf("unknown source '%s' (one of %s)", params.Args.Source, strings.Join(names, ", "))
+}
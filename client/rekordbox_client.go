@@ -2,304 +2,305 @@ package client
 
 import (
 	"context"
-	"fmt"
-	"os"
+	"database/sql"
+	"log"
 	"sort"
 	"strings"
+	"sync"
 
 	"github.com/xdave/keyid/args"
 	"github.com/xdave/keyid/interfaces"
 	"github.com/xdave/keyid/models"
-	"github.com/xdave/keyid/util"
 
 	"github.com/dvcrn/go-rekordbox/rekordbox"
 	"github.com/mattn/go-nulltype"
 	"go.uber.org/fx"
 )
 
+// Above this many changed tracks, reading the whole djmdContent table is
+// faster than looking the tracks up one by one.
+const rekordboxContentBatch = 200
+
 type RekordboxClient struct {
+	args            *args.Args
 	client          *rekordbox.Client
+	databasePath    string
 	optionsResolver *RekordboxOptionsResolver
-	history         *RekordboxHistory
-	args            *args.Args
-	printer         interfaces.Printer
-	shutdowner      fx.Shutdowner
+	// cacheMu keeps loads from different goroutines off the cache
+	cacheMu sync.Mutex
+	cache   *trackCache
 }
 
-type RekordboxClientParams struct {
-	fx.In
-	fx.Lifecycle
-	fx.Shutdowner
-	OptionsResolver *RekordboxOptionsResolver
-	History         *RekordboxHistory
-	Args            *args.Args
-	Printer         interfaces.Printer
+var rekordboxBackend = &Backend{
+	Name: interfaces.SourceRekordbox,
+	Capabilities: []interfaces.Capability{
+		interfaces.CapabilityNowPlaying,
+		interfaces.CapabilityPlaylistTree,
+		interfaces.CapabilityTags,
+	},
+	New: NewRekordboxClient,
 }
 
-type RekordboxClientResult struct {
-	fx.Out
-	Client interfaces.Client
+func NewRekordboxBackend() BackendResult {
+	return BackendResult{Backend: rekordboxBackend}
 }
 
-func NewRekordboxClient(params RekordboxClientParams) RekordboxClientResult {
-	optionsFilePath := params.OptionsResolver.Resolve()
+func NewRekordboxClient(params LibraryParams) (LibraryResult, error) {
+	optionsFilePath, err := params.OptionsResolver.Resolve()
+	if err != nil {
+		return LibraryResult{}, err
+	}
+
+	// rekordbox.NewClient panics when options.json is missing or incomplete
+	databasePath, err := params.OptionsResolver.DatabasePath(optionsFilePath)
+	if err != nil {
+		return LibraryResult{}, err
+	}
 
 	client, err := rekordbox.NewClient(optionsFilePath)
 	if err != nil {
-		panic(err)
+		return LibraryResult{}, databaseError(databasePath, err)
 	}
 
 	rbClient := &RekordboxClient{
+		args:            params.Args,
 		client:          client,
+		databasePath:    databasePath,
 		optionsResolver: params.OptionsResolver,
-		history:         params.History,
-		args:            params.Args,
-		printer:         params.Printer,
-		shutdowner:      params.Shutdowner,
+		cache:           openTrackCache(params.Args, interfaces.SourceRekordbox, databasePath),
 	}
 
 	params.Lifecycle.Append(fx.Hook{
 		OnStop: func(ctx context.Context) error {
-			rbClient.Close()
-			return nil
+			return rbClient.Close()
 		},
 	})
 
-	return RekordboxClientResult{
-		Client: rbClient,
-	}
+	return LibraryResult{
+		Library: rbClient,
+	}, nil
 }
 
-func (c *RekordboxClient) LoadPlaylist(name string) interfaces.Collection {
+func (c *RekordboxClient) LoadPlaylist(ctx context.Context, name string) (interfaces.Collection, error) {
 	tracks := []interfaces.Item{}
 
-	if c.args.Playlist == "" {
-		allContent, _ := c.client.AllDjmdContent(context.Background())
-		for _, content := range allContent {
-			tracks = append(tracks, NewTrackFromContent(c.client, content))
+	contentIDs, byID, err := c.loadCollection(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if name == "" {
+		for _, id := range contentIDs {
+			if track, ok := byID[id]; ok {
+				tracks = append(tracks, track)
+			}
 		}
 	} else {
-		playlists, _ := c.client.DjmdPlaylistByName(context.Background(), nulltype.NullStringOf(name))
+		playlists, err := c.client.DjmdPlaylistByName(ctx, nulltype.NullStringOf(name))
+		if err != nil {
+			return nil, databaseError(c.databasePath, err)
+		}
 		if len(playlists) == 0 {
-			err := fmt.Sprintf("Error: cannot find a playlist with name '%s'", name)
-			fmt.Fprintln(os.Stderr, err)
-			return nil
+			return nil, &interfaces.PlaylistNotFoundError{Name: name}
 		}
 		playlist := playlists[0]
-		playlistSongs, _ := c.client.DjmdSongPlaylistByPlaylistID(context.Background(), playlist.ID)
+		playlistSongs, err := c.client.DjmdSongPlaylistByPlaylistID(ctx, playlist.ID)
+		if err != nil {
+			return nil, databaseError(c.databasePath, err)
+		}
 		sort.Slice(playlistSongs, func(i, j int) bool {
 			return playlistSongs[i].TrackNo.Int64Value() < playlistSongs[j].TrackNo.Int64Value()
 		})
+
 		for _, song := range playlistSongs {
-			content, _ := c.client.DjmdContentByID(context.Background(), song.ContentID)
-			tracks = append(tracks, NewTrackFromContent(c.client, content))
+			track, ok := byID[song.ContentID.StringValue()]
+			if !ok {
+				// The track was removed from the collection
+				continue
+			}
+			tracks = append(tracks, track)
 		}
 	}
 
 	return models.NewInMemoryCollection(tracks...).Filter(func(i interfaces.Item) bool {
 		return strings.Compare(i.GetDateAdded(), c.args.From) > 0
-	})
+	}), nil
 }
 
-func (c *RekordboxClient) GetPlaylists() []*interfaces.PlaylistNode {
-	playlists, _ := c.client.AllDjmdPlaylist(context.Background())
-	playlistMap := make(map[string]*interfaces.PlaylistNode)
-	for _, playlist := range playlists {
-		playlistMap[playlist.ID.StringValue()] = &interfaces.PlaylistNode{
-			ID:       playlist.ID.StringValue(),
-			Name:     playlist.Name.StringValue(),
-			Children: []*interfaces.PlaylistNode{},
-		}
+// loadCollection returns the content IDs of the whole collection in database
+// order along with their tracks. Tracks come from the cache unless their
+// djmdContent row changed since they were cached.
+func (c *RekordboxClient) loadCollection(ctx context.Context) ([]string, map[string]interfaces.Item, error) {
+	c.cacheMu.Lock()
+	defer c.cacheMu.Unlock()
+
+	stamp, err := c.lookupStamp(ctx)
+	if err != nil {
+		return nil, nil, databaseError(c.databasePath, err)
+	}
+	contentIDs, versions, err := c.contentVersions(ctx)
+	if err != nil {
+		return nil, nil, databaseError(c.databasePath, err)
 	}
+	c.cache.reset(stamp)
 
-	var roots []*interfaces.PlaylistNode
-	for _, playlist := range playlists {
-		if playlist.ParentID.StringValue() == "" || playlist.ParentID.StringValue() == "root" {
-			roots = append(roots, playlistMap[playlist.ID.StringValue()])
+	byID := make(map[string]interfaces.Item, len(contentIDs))
+	changed := []string{}
+	for _, id := range contentIDs {
+		if track, ok := c.cache.get(id, versions[id]); ok {
+			byID[id] = track
 		} else {
-			parent, ok := playlistMap[playlist.ParentID.StringValue()]
-			if ok {
-				parent.Children = append(parent.Children, playlistMap[playlist.ID.StringValue()])
-			}
+			changed = append(changed, id)
 		}
 	}
 
-	return roots
-}
-
-func (c *RekordboxClient) GetTrackByTitle(pattern string, from interfaces.Collection) interfaces.Item {
-	for _, track := range from.Items() {
-		if strings.Contains(strings.ToLower(track.GetTitle()), strings.ToLower(pattern)) {
-			return track
+	if len(changed) > 0 {
+		contents, err := c.changedContent(ctx, changed)
+		if err != nil {
+			return nil, nil, databaseError(c.databasePath, err)
+		}
+		lookup, err := loadRekordboxLookup(ctx, c.client)
+		if err != nil {
+			return nil, nil, databaseError(c.databasePath, err)
+		}
+		for _, content := range contents {
+			id := content.ID.StringValue()
+			track := lookup.newTrack(content)
+			byID[id] = track
+			c.cache.put(id, versions[id], track)
 		}
 	}
 
-	err := fmt.Sprintf("Error: cannot find a track with '%s' in the name", c.args.StartWith)
-	fmt.Fprintln(os.Stderr, err)
-	return nil
-}
-
-func (c *RekordboxClient) GetNowPlaying(collection interfaces.Collection) interfaces.Item {
-	if c.args.StartWith != "" {
-		return c.GetTrackByTitle(c.args.StartWith, collection)
+	c.cache.prune(func(id string) bool {
+		_, ok := versions[id]
+		return ok
+	})
+	if err := c.cache.save(); err != nil {
+		log.Default().Printf("cannot write track cache: %v", err)
 	}
 
-	songHistories, _ := c.client.RecentDjmdSongHistory(context.Background(), 1)
-	if len(songHistories) == 0 {
-		return nil
-	}
-	item := songHistories[0]
-	content, err := c.client.DjmdContentByID(context.Background(), item.ContentID)
+	return contentIDs, byID, nil
+}
 
+// contentVersions lists every content ID with the usn and update time of its
+// row, which rekordbox bumps whenever it changes a track.
+func (c *RekordboxClient) contentVersions(ctx context.Context) ([]string, map[string]string, error) {
+	rows, err := c.client.GetDB().QueryContext(ctx, `
+		SELECT ID, ifnull(usn, '') || '/' || ifnull(rb_local_usn, '') || '/' || ifnull(updated_at, '')
+		FROM djmdContent`)
 	if err != nil {
-		panic(err)
+		return nil, nil, err
 	}
-
-	track := NewTrackFromContent(c.client, content)
-	c.history.Add(track)
-	return track
-}
-
-func (c *RekordboxClient) GetCompatibleTracks(track interfaces.Item, from interfaces.Collection) interfaces.Collection {
-	compat := models.NewInMemoryCollection()
-	tracks := models.NewInMemoryCollection()
-
-	from.ForEach(func(item interfaces.Item) {
-		if !c.history.Contains(item) {
-			if !item.Equals(track) && track.IsCompatible(item.AsBpm(track.GetBPM())) {
-				compat.Add(item)
-			}
-		}
-	})
-
-	excludeTags := util.StringSlice(strings.Split(c.args.ExcludeTags, ","))
-	tags := util.StringSlice(strings.Split(c.args.Tags, ","))
-
-	if len(tags) > 0 {
-		for _, item := range compat.Items() {
-			itemTags := util.StringSlice(item.GetTags())
-			if itemTags.ContainsAnyOf(tags) {
-				if !tracks.Contains(item) {
-					tracks.Add(item)
-				}
-			}
+	defer rows.Close()
+
+	contentIDs := []string{}
+	versions := make(map[string]string)
+	for rows.Next() {
+		var id, version string
+		if err := rows.Scan(&id, &version); err != nil {
+			return nil, nil, err
 		}
+		contentIDs = append(contentIDs, id)
+		versions[id] = version
 	}
+	return contentIDs, versions, rows.Err()
+}
 
-	if len(excludeTags) > 0 {
-		for _, item := range compat.Items() {
-			itemTags := util.StringSlice(item.GetTags())
-			if !itemTags.ContainsAnyOf(excludeTags) {
-				if !tracks.Contains(item) {
-					tracks.Add(item)
-				}
-			}
+// lookupStamp changes whenever a key, artist, my tag or my tag link is
+// added, changed or removed, since cached tracks hold their names.
+func (c *RekordboxClient) lookupStamp(ctx context.Context) (string, error) {
+	stamp := ""
+	for _, table := range []string{"djmdKey", "djmdArtist", "djmdMyTag", "djmdSongMyTag"} {
+		var tableStamp string
+		err := c.client.GetDB().QueryRowContext(ctx, `
+			SELECT count(*) || '/' || ifnull(max(rb_local_usn), '') || '/' || ifnull(max(updated_at), '')
+			FROM `+table).Scan(&tableStamp)
+		if err != nil {
+			return "", err
 		}
+		stamp += table + ":" + tableStamp + ";"
 	}
-
-	if len(tracks.Items()) > 0 {
-		return tracks
-	}
-
-	return compat
+	return stamp, nil
 }
 
-func (c *RekordboxClient) Run() {
-	collection := c.LoadPlaylist(c.args.Playlist)
-
-	if collection == nil {
-		c.shutdowner.Shutdown(fx.ExitCode(1))
-		return
+func (c *RekordboxClient) changedContent(ctx context.Context, contentIDs []string) ([]*rekordbox.DjmdContent, error) {
+	if len(contentIDs) > rekordboxContentBatch {
+		return c.client.AllDjmdContent(ctx)
 	}
 
-	if c.args.Mode == interfaces.ModeSuggest {
-		c.Suggest(collection)
-	} else if c.args.Mode == interfaces.ModeGenerate {
-		c.Generate(collection)
+	contents := []*rekordbox.DjmdContent{}
+	for _, id := range contentIDs {
+		content, err := c.client.DjmdContentByID(ctx, nulltype.NullStringOf(id))
+		if err == sql.ErrNoRows {
+			// Removed since the versions were read
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		contents = append(contents, content)
 	}
+	return contents, nil
 }
 
-func (c *RekordboxClient) Suggest(collection interfaces.Collection) interfaces.Collection {
-	track := c.GetNowPlaying(collection)
+func (c *RekordboxClient) GetPlaylists(ctx context.Context) ([]*interfaces.PlaylistNode, error) {
+	playlists, err := c.client.AllDjmdPlaylist(ctx)
+	if err != nil {
+		return nil, databaseError(c.databasePath, err)
+	}
+	playlistMap := make(map[string]*interfaces.PlaylistNode)
+	for _, playlist := range playlists {
+		playlistMap[playlist.ID.StringValue()] = &interfaces.PlaylistNode{
+			ID:       playlist.ID.StringValue(),
+			Name:     playlist.Name.StringValue(),
+			Children: []*interfaces.PlaylistNode{},
+		}
+	}
 
-	if track == nil {
-		return models.NewInMemoryCollection()
+	var roots []*interfaces.PlaylistNode
+	for _, playlist := range playlists {
+		if playlist.ParentID.StringValue() == "" || playlist.ParentID.StringValue() == "root" {
+			roots = append(roots, playlistMap[playlist.ID.StringValue()])
+		} else {
+			parent, ok := playlistMap[playlist.ParentID.StringValue()]
+			if ok {
+				parent.Children = append(parent.Children, playlistMap[playlist.ID.StringValue()])
+			}
+		}
 	}
 
-	return c.GetCompatibleTracks(track, collection)
+	return roots, nil
 }
 
-func (c *RekordboxClient) Generate(collection interfaces.Collection) interfaces.Collection {
-	crate := models.NewInMemoryCollection(collection.Items()...)
-
-	if c.args.Random {
-		crate.RandomShuffle()
+func (c *RekordboxClient) GetNowPlaying(ctx context.Context) (interfaces.Item, error) {
+	songHistories, err := c.client.RecentDjmdSongHistory(ctx, 1)
+	if err != nil {
+		return nil, databaseError(c.databasePath, err)
 	}
-
-	startWith := c.GetTrackByTitle(c.args.StartWith, crate)
-
-	if startWith == nil {
-		return models.NewInMemoryCollection()
+	if len(songHistories) == 0 {
+		return nil, nil
+	}
+	item := songHistories[0]
+	content, err := c.client.DjmdContentByID(ctx, item.ContentID)
+	if err == sql.ErrNoRows {
+		return nil, &interfaces.TrackNotFoundError{Pattern: item.ContentID.String()}
+	}
+	if err != nil {
+		return nil, databaseError(c.databasePath, err)
 	}
 
-	playlist := models.NewInMemoryCollection(startWith)
-
-	var lastTrack interfaces.Item
-	retries := 10
-
-	for retries > 0 {
-		lastTrack = playlist.Last()
-
-		compatible := c.GetCompatibleTracks(lastTrack, crate)
-		if c.args.Random {
-			compatible.RandomShuffle()
-		}
-
-		if playlist.Len() == crate.Len() {
-			break
-		}
-		hasCompatibleTrack := false
-		if compatible.IsEmpty() {
-			hasCompatibleTrack = false
-		}
-		for _, track := range compatible.Items() {
-			if playlist.Contains(track) {
-				continue
-			}
-			hasCompatibleTrack = true
-			playlist.Add(track)
-			if c.args.Random {
-				compatible.RandomShuffle()
-			}
-			break
-
-		}
-		if !hasCompatibleTrack {
-			for _, track := range crate.Filter(func(i interfaces.Item) bool {
-				return !playlist.Contains(i)
-			}).SortWith(func(a, b interfaces.Item) bool {
-				return a.GetScale().IsCompatible(lastTrack.GetScale())
-			}).Items() {
-				if lastTrack.BpmMatchesTarget(track.GetBPM()) {
-					//fmt.Fprintln(os.Stderr, "Bpm match result:", lastTrack.BpmMatchesTarget(track.GetBPM()))
-					fmt.Fprintln(os.Stderr, "BPM jump from", lastTrack.GetBPM(), "to", track.GetBPM())
-					fmt.Fprintln(os.Stderr, "Adding random track:", track)
-					playlist.Add(track)
-					break
-				} else if retries <= 5 {
-					fmt.Fprintln(os.Stderr, "Adding random track (ignoring BPM):", track)
-					playlist.Add(track)
-					break
-				}
-			}
-			retries -= 1
-			// break
-		}
+	track, err := NewTrackFromContent(ctx, c.client, content)
+	if err != nil {
+		return nil, databaseError(c.databasePath, err)
 	}
+	return track, nil
+}
 
-	return playlist
+func (c *RekordboxClient) Supports(capability interfaces.Capability) bool {
+	return rekordboxBackend.Supports(capability)
 }
 
-func (c *RekordboxClient) Close() {
+func (c *RekordboxClient) Close() error {
 	c.client.Close()
+	return nil
 }
@@ -0,0 +1,96 @@
+package client
+
+import (
+	"context"
+
+	"github.com/xdave/keyid/interfaces"
+	"github.com/xdave/keyid/models"
+	"github.com/xdave/keyid/util"
+
+	"github.com/dvcrn/go-rekordbox/rekordbox"
+)
+
+// rekordboxLookup resolves the key, artist and my tag IDs of DjmdContent rows
+// to names in memory, so loading a collection doesn't cost several queries
+// per track.
+type rekordboxLookup struct {
+	scales  map[string]string
+	artists map[string]string
+	// my tag names by content ID
+	tags map[string][]string
+}
+
+func newRekordboxLookup() *rekordboxLookup {
+	return &rekordboxLookup{
+		scales:  make(map[string]string),
+		artists: make(map[string]string),
+		tags:    make(map[string][]string),
+	}
+}
+
+// loadRekordboxLookup reads every key, artist, my tag and my tag link once.
+func loadRekordboxLookup(ctx context.Context, client *rekordbox.Client) (*rekordboxLookup, error) {
+	lookup := newRekordboxLookup()
+
+	keys, err := client.AllDjmdKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		lookup.scales[key.ID.StringValue()] = key.ScaleName.String()
+	}
+
+	artists, err := client.AllDjmdArtist(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, artist := range artists {
+		lookup.artists[artist.ID.StringValue()] = artist.Name.String()
+	}
+
+	myTags, err := client.AllDjmdMyTag(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tagNames := make(map[string]string, len(myTags))
+	for _, tag := range myTags {
+		tagNames[tag.ID.StringValue()] = tag.Name.String()
+	}
+
+	songTags, err := client.AllDjmdSongMyTag(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, songTag := range songTags {
+		if name, ok := tagNames[songTag.MyTagID.StringValue()]; ok {
+			contentID := songTag.ContentID.StringValue()
+			lookup.tags[contentID] = append(lookup.tags[contentID], name)
+		}
+	}
+
+	return lookup, nil
+}
+
+func (l *rekordboxLookup) newTrack(content *rekordbox.DjmdContent) interfaces.Item {
+	scaleName := l.scales[content.KeyID.StringValue()]
+
+	artistName, ok := l.artists[content.ArtistID.StringValue()]
+	if !ok {
+		artistName = "<none>"
+	}
+
+	tags := []string{}
+	tags = append(tags, l.tags[content.ID.StringValue()]...)
+
+	return &Track{
+		ID:        content.ID.String(),
+		BPM:       float64(content.BPM.Int64Value()) / 100.0,
+		Scale:     models.NewKey(scaleName),
+		Artist:    artistName,
+		Title:     content.Title.String(),
+		Energy:    util.ParseEnergy(content.Commnt.String()),
+		Path:      content.FolderPath.String(),
+		DateAdded: content.DateCreated.String(),
+		Tags:      tags,
+	}
+}
@@ -0,0 +1,225 @@
+package client
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/xdave/keyid/interfaces"
+
+	"github.com/dvcrn/go-rekordbox/rekordbox"
+)
+
+// The key go-rekordbox opens every master.db with
+const rekordboxFixtureKey = "402fd482c38817c35ffa8ffb8c7d93143b749e7d315df7a81732a1ff43608497"
+
+// rekordboxColumns are the columns whose name isn't the field name, or the
+// json name for rekordbox's own rb_ columns
+var rekordboxColumns = map[string]string{
+	"RbLocalFolderPath": "rb_LocalFolderPath",
+}
+
+// fixtureTable creates the table of row, a go-rekordbox row struct, with its
+// columns in field order, since go-rekordbox selects * and scans by position.
+// It returns the columns and the value each one defaults to.
+func fixtureTable(t testing.TB, db *sql.DB, table string, row any) ([]string, []any) {
+	columns := []string{}
+	defaults := []any{}
+	rowType := reflect.TypeOf(row)
+	timeType := reflect.TypeOf(rekordbox.Time{})
+	for i := 0; i < rowType.NumField(); i++ {
+		field := rowType.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		column := field.Name
+		if name, ok := rekordboxColumns[field.Name]; ok {
+			column = name
+		} else if tag := strings.Split(field.Tag.Get("json"), ",")[0]; strings.HasPrefix(tag, "rb_") || tag == "usn" || tag == "created_at" || tag == "updated_at" {
+			column = tag
+		}
+		columns = append(columns, column)
+		// NULL can't be scanned into a Time
+		if field.Type == timeType {
+			defaults = append(defaults, "2024-01-01 00:00:00")
+		} else {
+			defaults = append(defaults, nil)
+		}
+	}
+	if _, err := db.Exec(fmt.Sprintf("CREATE TABLE %s (%s)", table, strings.Join(columns, ", "))); err != nil {
+		t.Fatal(err)
+	}
+	return columns, defaults
+}
+
+// insertFixtureRows inserts a row for each of values, a column to value map
+// leaving every other column at its default.
+func insertFixtureRows(t testing.TB, db *sql.DB, table string, row any, values []map[string]any) {
+	columns, defaults := fixtureTable(t, db, table, row)
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	insert, err := tx.Prepare(fmt.Sprintf("INSERT INTO %s VALUES (%s)", table, placeholders))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer insert.Close()
+	for _, value := range values {
+		args := append([]any{}, defaults...)
+		for i, column := range columns {
+			if v, ok := value[column]; ok {
+				args[i] = v
+			}
+		}
+		if _, err := insert.Exec(args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// newRekordboxFixture generates an encrypted master.db of tracks tracks, with
+// their keys, artists and two my tags each, and opens it the way
+// NewRekordboxClient does.
+func newRekordboxFixture(t testing.TB, tracks int) *rekordbox.Client {
+	dir := t.TempDir()
+	databasePath := filepath.Join(dir, "master.db")
+	db, err := sql.Open("sqlite3", fmt.Sprintf("file:%s?_key=%s", databasePath, rekordboxFixtureKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	keys := []string{"Am", "Em", "Bm", "F#m", "C#m", "G#m", "D#m", "Bbm", "Fm", "Cm", "Gm", "Dm",
+		"C", "G", "D", "A", "E", "B", "F#", "Db", "Ab", "Eb", "Bb", "F"}
+	keyRows := []map[string]any{}
+	for i, key := range keys {
+		keyRows = append(keyRows, map[string]any{"ID": fmt.Sprint(i + 1), "ScaleName": key})
+	}
+	insertFixtureRows(t, db, "djmdKey", rekordbox.DjmdKey{}, keyRows)
+
+	artistRows := []map[string]any{}
+	for i := 1; i <= 100; i++ {
+		artistRows = append(artistRows, map[string]any{"ID": fmt.Sprint(i), "Name": fmt.Sprint("Artist ", i)})
+	}
+	insertFixtureRows(t, db, "djmdArtist", rekordbox.DjmdArtist{}, artistRows)
+
+	tagRows := []map[string]any{}
+	for i := 1; i <= 10; i++ {
+		tagRows = append(tagRows, map[string]any{"ID": fmt.Sprint(i), "Name": fmt.Sprint("tag ", i)})
+	}
+	insertFixtureRows(t, db, "djmdMyTag", rekordbox.DjmdMyTag{}, tagRows)
+
+	contentRows := []map[string]any{}
+	songTagRows := []map[string]any{}
+	for i := 1; i <= tracks; i++ {
+		id := fmt.Sprint(i)
+		contentRows = append(contentRows, map[string]any{
+			"ID":          id,
+			"Title":       fmt.Sprint("Track ", i),
+			"BPM":         12000 + i%1000,
+			"KeyID":       fmt.Sprint(i%len(keys) + 1),
+			"ArtistID":    fmt.Sprint(i%100 + 1),
+			"Commnt":      fmt.Sprint("Energy ", i%10),
+			"FolderPath":  fmt.Sprintf("/Music/track%d.mp3", i),
+			"DateCreated": "2024-01-01",
+		})
+		for j := 0; j < 2; j++ {
+			songTagRows = append(songTagRows, map[string]any{
+				"ID":        fmt.Sprintf("%d-%d", i, j),
+				"MyTagID":   fmt.Sprint((i+j)%10 + 1),
+				"ContentID": id,
+			})
+		}
+	}
+	insertFixtureRows(t, db, "djmdContent", rekordbox.DjmdContent{}, contentRows)
+	insertFixtureRows(t, db, "djmdSongMyTag", rekordbox.DjmdSongMyTag{}, songTagRows)
+
+	optionsPath := filepath.Join(dir, "options.json")
+	options := fmt.Sprintf(`{"options":[["db-path",%q]]}`, databasePath)
+	if err := os.WriteFile(optionsPath, []byte(options), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	client, err := rekordbox.NewClient(optionsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(client.Close)
+	return client
+}
+
+func loadFixtureContent(t testing.TB, client *rekordbox.Client) []*rekordbox.DjmdContent {
+	contents, err := client.AllDjmdContent(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return contents
+}
+
+func TestRekordboxLookupMatchesPerTrack(t *testing.T) {
+	ctx := context.Background()
+	client := newRekordboxFixture(t, 50)
+	lookup, err := loadRekordboxLookup(ctx, client)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	contents := loadFixtureContent(t, client)
+	if len(contents) != 50 {
+		t.Fatalf("fixture has %d tracks, want 50", len(contents))
+	}
+	for _, content := range contents {
+		want, err := NewTrackFromContent(ctx, client, content)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := lookup.newTrack(content)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("lookup built %v, per track %v", got, want)
+		}
+		if !got.GetScale().IsKnown() || len(got.GetTags()) != 2 || got.GetArtist() == "<none>" {
+			t.Errorf("fixture track %v is missing its key, artist or tags", got)
+		}
+	}
+}
+
+// The queries for a collection of tracks, per track or all at once
+func BenchmarkRekordboxTracks(b *testing.B) {
+	ctx := context.Background()
+	client := newRekordboxFixture(b, 2000)
+	contents := loadFixtureContent(b, client)
+
+	b.Run("PerTrack", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			tracks := make([]interfaces.Item, 0, len(contents))
+			for _, content := range contents {
+				track, err := NewTrackFromContent(ctx, client, content)
+				if err != nil {
+					b.Fatal(err)
+				}
+				tracks = append(tracks, track)
+			}
+		}
+	})
+	b.Run("Bulk", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			lookup, err := loadRekordboxLookup(ctx, client)
+			if err != nil {
+				b.Fatal(err)
+			}
+			tracks := make([]interfaces.Item, 0, len(contents))
+			for _, content := range contents {
+				tracks = append(tracks, lookup.newTrack(content))
+			}
+		}
+	})
+}
@@ -1,6 +1,8 @@
 package client
 
 import (
+	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -25,22 +27,44 @@ func NewRekordboxOptionsResolver(params RekordboxOptionsResolverParams) Rekordbo
 	}
 }
 
-func (r *RekordboxOptionsResolver) Resolve() string {
+func (r *RekordboxOptionsResolver) Resolve() (string, error) {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
-		panic(err)
+		return "", err
 	}
 
 	switch runtime.GOOS {
 	case "darwin":
 		{
-			return filepath.Join(homeDir, "Library", "Application Support", "Pioneer", "rekordboxAgent", "storage", "options.json")
+			return filepath.Join(homeDir, "Library", "Application Support", "Pioneer", "rekordboxAgent", "storage", "options.json"), nil
 		}
 	case "windows":
 		{
 			// Might not work, not tested on Windows
-			return filepath.Join(homeDir, "AppData", "Local", "Pioneer", "rekordboxAgent", "storage", "options.json")
+			return filepath.Join(homeDir, "AppData", "Local", "Pioneer", "rekordboxAgent", "storage", "options.json"), nil
 		}
 	}
-	panic("Cannot determine Rekordbox db options path, unsupported OS (mac & windows only)")
+	return "", errors.New("cannot determine Rekordbox db options path, unsupported OS (mac & windows only), use -xml with a collection.xml export instead")
+}
+
+// DatabasePath reads the location of master.db from rekordbox's options.json,
+// which looks like {"options":[["db-path","/path/to/master.db"], ...]}. The
+// db-path has to come first, since that is the option rekordbox.NewClient opens.
+func (r *RekordboxOptionsResolver) DatabasePath(optionsFilePath string) (string, error) {
+	data, err := os.ReadFile(optionsFilePath)
+	if err != nil {
+		return "", err
+	}
+
+	var config struct {
+		Options [][]string `json:"options"`
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return "", err
+	}
+
+	if len(config.Options) == 0 || len(config.Options[0]) != 2 || config.Options[0][0] != "db-path" {
+		return "", errors.New("no db-path first in " + optionsFilePath)
+	}
+	return config.Options[0][1], nil
 }
@@ -0,0 +1,43 @@
+package client
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRekordboxDatabasePath(t *testing.T) {
+	tests := []struct {
+		name    string
+		options string
+		want    string
+		wantErr bool
+	}{
+		{"db-path first", `{"options":[["db-path","/music/master.db"],["language","en"]]}`, "/music/master.db", false},
+		{"db-path not first", `{"options":[["language","en"],["db-path","/music/master.db"]]}`, "", true},
+		{"db-path without a value", `{"options":[["db-path"]]}`, "", true},
+		{"db-path with extra values", `{"options":[["db-path","/music/master.db","x"]]}`, "", true},
+		{"no options", `{"options":[]}`, "", true},
+		{"not JSON", `options`, "", true},
+	}
+	resolver := &RekordboxOptionsResolver{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "options.json")
+			if err := os.WriteFile(path, []byte(tt.options), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := resolver.DatabasePath(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DatabasePath() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("DatabasePath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := resolver.DatabasePath(filepath.Join(t.TempDir(), "options.json")); err == nil {
+		t.Error("DatabasePath() of a missing file didn't fail")
+	}
+}
@@ -0,0 +1,230 @@
+package client
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/xdave/keyid/args"
+	"github.com/xdave/keyid/interfaces"
+	"github.com/xdave/keyid/models"
+	"github.com/xdave/keyid/util"
+)
+
+const (
+	rekordboxXmlFolder   = "0"
+	rekordboxXmlPlaylist = "1"
+
+	rekordboxXmlKeyTrackID  = "0"
+	rekordboxXmlKeyLocation = "1"
+)
+
+type rekordboxXml struct {
+	Collection struct {
+		Tracks []*rekordboxXmlTrack `xml:"TRACK"`
+	} `xml:"COLLECTION"`
+	Playlists struct {
+		Root *rekordboxXmlNode `xml:"NODE"`
+	} `xml:"PLAYLISTS"`
+}
+
+type rekordboxXmlTrack struct {
+	TrackID    string `xml:"TrackID,attr"`
+	Name       string `xml:"Name,attr"`
+	Artist     string `xml:"Artist,attr"`
+	AverageBpm string `xml:"AverageBpm,attr"`
+	Tonality   string `xml:"Tonality,attr"`
+	Comments   string `xml:"Comments,attr"`
+	DateAdded  string `xml:"DateAdded,attr"`
+	Location   string `xml:"Location,attr"`
+}
+
+type rekordboxXmlNode struct {
+	Name    string              `xml:"Name,attr"`
+	Type    string              `xml:"Type,attr"`
+	KeyType string              `xml:"KeyType,attr"`
+	Nodes   []*rekordboxXmlNode `xml:"NODE"`
+	Tracks  []struct {
+		Key string `xml:"Key,attr"`
+	} `xml:"TRACK"`
+}
+
+// RekordboxXmlClient reads tracks and playlists from a rekordbox collection.xml
+// export, so it works on platforms where the rekordbox database is unavailable.
+type RekordboxXmlClient struct {
+	args       *args.Args
+	library    *rekordboxXml
+	tracks     []interfaces.Item
+	byID       map[string]interfaces.Item
+	byLocation map[string]interfaces.Item
+}
+
+var rekordboxXmlBackend = &Backend{
+	Name: interfaces.SourceXML,
+	Capabilities: []interfaces.Capability{
+		interfaces.CapabilityPlaylistTree,
+	},
+	New: NewRekordboxXmlClient,
+}
+
+func NewRekordboxXmlBackend() BackendResult {
+	return BackendResult{Backend: rekordboxXmlBackend}
+}
+
+func NewRekordboxXmlClient(params LibraryParams) (LibraryResult, error) {
+	if params.Args.XML == "" {
+		return LibraryResult{}, errors.New("the 'xml' source needs the path of a collection.xml export (-xml)")
+	}
+
+	data, err := os.ReadFile(params.Args.XML)
+	if err != nil {
+		return LibraryResult{}, err
+	}
+
+	library := &rekordboxXml{}
+	if err := xml.Unmarshal(data, library); err != nil {
+		return LibraryResult{}, err
+	}
+
+	xmlClient := &RekordboxXmlClient{
+		args:       params.Args,
+		library:    library,
+		byID:       make(map[string]interfaces.Item),
+		byLocation: make(map[string]interfaces.Item),
+	}
+
+	for _, entry := range library.Collection.Tracks {
+		track := NewTrackFromXml(entry)
+		xmlClient.tracks = append(xmlClient.tracks, track)
+		xmlClient.byID[entry.TrackID] = track
+		xmlClient.byLocation[entry.Location] = track
+	}
+
+	return LibraryResult{
+		Library: xmlClient,
+	}, nil
+}
+
+func NewTrackFromXml(entry *rekordboxXmlTrack) interfaces.Item {
+	bpm, _ := strconv.ParseFloat(entry.AverageBpm, 64)
+
+	scaleName := entry.Tonality
+
+	artistName := entry.Artist
+	if artistName == "" {
+		artistName = "<none>"
+	}
+
+	return &Track{
+		ID:        entry.TrackID,
+		BPM:       bpm,
+		Scale:     models.NewKey(scaleName),
+		Artist:    artistName,
+		Title:     entry.Name,
+		Energy:    util.ParseEnergy(entry.Comments),
+		Path:      locationToPath(entry.Location),
+		DateAdded: entry.DateAdded,
+		Tags:      []string{},
+	}
+}
+
+// locationToPath converts a rekordbox "file://localhost/..." URL to a file path.
+func locationToPath(location string) string {
+	u, err := url.Parse(location)
+	if err != nil || u.Scheme != "file" {
+		return location
+	}
+	path := u.Path
+	// Windows locations look like file://localhost/C:/Music/track.mp3
+	if len(path) > 2 && path[0] == '/' && path[2] == ':' {
+		path = path[1:]
+	}
+	return path
+}
+
+func (c *RekordboxXmlClient) LoadPlaylist(ctx context.Context, name string) (interfaces.Collection, error) {
+	tracks := []interfaces.Item{}
+
+	if name == "" {
+		tracks = append(tracks, c.tracks...)
+	} else {
+		playlist := c.findPlaylist(c.library.Playlists.Root, name)
+		if playlist == nil {
+			return nil, &interfaces.PlaylistNotFoundError{Name: name}
+		}
+		for _, entry := range playlist.Tracks {
+			var track interfaces.Item
+			var ok bool
+			if playlist.KeyType == rekordboxXmlKeyLocation {
+				track, ok = c.byLocation[entry.Key]
+			} else {
+				track, ok = c.byID[entry.Key]
+			}
+			if ok {
+				tracks = append(tracks, track)
+			}
+		}
+	}
+
+	return models.NewInMemoryCollection(tracks...).Filter(func(i interfaces.Item) bool {
+		return strings.Compare(i.GetDateAdded(), c.args.From) > 0
+	}), nil
+}
+
+func (c *RekordboxXmlClient) findPlaylist(node *rekordboxXmlNode, name string) *rekordboxXmlNode {
+	if node == nil {
+		return nil
+	}
+	if node.Type == rekordboxXmlPlaylist && node.Name == name {
+		return node
+	}
+	for _, child := range node.Nodes {
+		if found := c.findPlaylist(child, name); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func (c *RekordboxXmlClient) GetPlaylists(ctx context.Context) ([]*interfaces.PlaylistNode, error) {
+	root := c.library.Playlists.Root
+	if root == nil {
+		return nil, nil
+	}
+	return c.buildPlaylistNodes(root.Nodes, ""), nil
+}
+
+// buildPlaylistNodes converts the XML NODE tree, which has no IDs of its own,
+// using each node's position in the tree as its ID.
+func (c *RekordboxXmlClient) buildPlaylistNodes(nodes []*rekordboxXmlNode, parentID string) []*interfaces.PlaylistNode {
+	playlists := []*interfaces.PlaylistNode{}
+	for i, node := range nodes {
+		id := strconv.Itoa(i)
+		if parentID != "" {
+			id = parentID + "." + id
+		}
+		playlists = append(playlists, &interfaces.PlaylistNode{
+			ID:       id,
+			Name:     node.Name,
+			Children: c.buildPlaylistNodes(node.Nodes, id),
+		})
+	}
+	return playlists
+}
+
+// GetNowPlaying never knows the track, since the XML export has no history.
+func (c *RekordboxXmlClient) GetNowPlaying(ctx context.Context) (interfaces.Item, error) {
+	return nil, nil
+}
+
+func (c *RekordboxXmlClient) Supports(capability interfaces.Capability) bool {
+	return rekordboxXmlBackend.Supports(capability)
+}
+
+func (c *RekordboxXmlClient) Close() error {
+	return nil
+}
@@ -0,0 +1,306 @@
+package client
+
+import (
+	"context"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"github.com/xdave/keyid/args"
+	"github.com/xdave/keyid/interfaces"
+	"github.com/xdave/keyid/models"
+	"github.com/xdave/keyid/util"
+)
+
+// Serato crate names use "%%" to separate parent and child crates
+const seratoCrateSeparator = "%%"
+
+// Field of a session "adat" record holding the full path of the played track
+const seratoSessionPath = 2
+
+// seratoField is one tag-length-value record from a Serato binary file.
+type seratoField struct {
+	tag  string
+	data []byte
+}
+
+// readSeratoFields splits Serato's "4 byte tag, 4 byte big-endian length,
+// payload" records, stopping at the first truncated record.
+func readSeratoFields(data []byte) []seratoField {
+	fields := []seratoField{}
+	for len(data) >= 8 {
+		tag := string(data[:4])
+		length := binary.BigEndian.Uint32(data[4:8])
+		data = data[8:]
+		if uint32(len(data)) < length {
+			break
+		}
+		fields = append(fields, seratoField{tag: tag, data: data[:length]})
+		data = data[length:]
+	}
+	return fields
+}
+
+// seratoString decodes the UTF-16BE strings Serato stores text and paths in.
+func seratoString(data []byte) string {
+	units := make([]uint16, 0, len(data)/2)
+	for i := 0; i+1 < len(data); i += 2 {
+		units = append(units, binary.BigEndian.Uint16(data[i:]))
+	}
+	return strings.TrimRight(string(utf16.Decode(units)), "\x00")
+}
+
+// SeratoClient reads tracks from a Serato "database V2" file and uses the
+// crates in "Subcrates" as playlists.
+type SeratoClient struct {
+	args      *args.Args
+	seratoDir string
+	root      string
+	tracks    []interfaces.Item
+	byPath    map[string]interfaces.Item
+	crates    []string
+}
+
+var seratoBackend = &Backend{
+	Name: interfaces.SourceSerato,
+	Capabilities: []interfaces.Capability{
+		interfaces.CapabilityNowPlaying,
+		interfaces.CapabilityPlaylistTree,
+	},
+	New: NewSeratoClient,
+}
+
+func NewSeratoBackend() BackendResult {
+	return BackendResult{Backend: seratoBackend}
+}
+
+func NewSeratoClient(params LibraryParams) (LibraryResult, error) {
+	seratoDir := params.Args.Serato
+	if seratoDir == "" {
+		var err error
+		if seratoDir, err = homePath("Music", "_Serato_"); err != nil {
+			return LibraryResult{}, err
+		}
+	}
+
+	database, err := os.ReadFile(filepath.Join(seratoDir, "database V2"))
+	if err != nil {
+		return LibraryResult{}, err
+	}
+
+	seratoClient := &SeratoClient{
+		args:      params.Args,
+		seratoDir: seratoDir,
+		root:      seratoRoot(seratoDir),
+		byPath:    make(map[string]interfaces.Item),
+	}
+
+	for _, field := range readSeratoFields(database) {
+		if field.tag != "otrk" {
+			continue
+		}
+		track := NewTrackFromSerato(readSeratoFields(field.data), seratoClient.root)
+		seratoClient.tracks = append(seratoClient.tracks, track)
+		seratoClient.byPath[track.GetPath()] = track
+	}
+
+	crateFiles, _ := filepath.Glob(filepath.Join(seratoDir, "Subcrates", "*.crate"))
+	for _, crateFile := range crateFiles {
+		seratoClient.crates = append(seratoClient.crates, strings.TrimSuffix(filepath.Base(crateFile), ".crate"))
+	}
+	sort.Strings(seratoClient.crates)
+
+	return LibraryResult{
+		Library: seratoClient,
+	}, nil
+}
+
+// seratoRoot returns the directory track paths are relative to. Serato stores
+// paths relative to the root of the drive the _Serato_ folder lives on, which
+// is the filesystem root for the library in the user's home directory.
+func seratoRoot(seratoDir string) string {
+	homeDir, err := os.UserHomeDir()
+	if err == nil && strings.HasPrefix(seratoDir, homeDir) {
+		return filepath.VolumeName(homeDir) + string(filepath.Separator)
+	}
+	return filepath.Dir(seratoDir)
+}
+
+func NewTrackFromSerato(fields []seratoField, root string) interfaces.Item {
+	track := &Track{
+		Artist: "<none>",
+		Tags:   []string{},
+	}
+	scaleName := ""
+
+	for _, field := range fields {
+		switch field.tag {
+		case "pfil":
+			track.Path = filepath.Join(root, filepath.FromSlash(seratoString(field.data)))
+			track.ID = track.Path
+		case "tsng":
+			track.Title = seratoString(field.data)
+		case "tart":
+			if artist := seratoString(field.data); artist != "" {
+				track.Artist = artist
+			}
+		case "tbpm":
+			track.BPM, _ = strconv.ParseFloat(seratoString(field.data), 64)
+		case "tkey":
+			if key := seratoString(field.data); key != "" {
+				scaleName = key
+			}
+		case "tcom":
+			track.Energy = util.ParseEnergy(seratoString(field.data))
+		case "uadd":
+			if len(field.data) == 4 {
+				added := time.Unix(int64(binary.BigEndian.Uint32(field.data)), 0)
+				track.DateAdded = added.Format(time.DateOnly)
+			}
+		}
+	}
+	track.Scale = models.NewKey(scaleName)
+
+	return track
+}
+
+func (c *SeratoClient) LoadPlaylist(ctx context.Context, name string) (interfaces.Collection, error) {
+	tracks := []interfaces.Item{}
+
+	if name == "" {
+		tracks = append(tracks, c.tracks...)
+	} else {
+		crate := c.findCrate(name)
+		if crate == "" {
+			return nil, &interfaces.PlaylistNotFoundError{Name: name}
+		}
+		data, err := os.ReadFile(filepath.Join(c.seratoDir, "Subcrates", crate+".crate"))
+		if err != nil {
+			return nil, err
+		}
+		for _, field := range readSeratoFields(data) {
+			if field.tag != "otrk" {
+				continue
+			}
+			for _, trackField := range readSeratoFields(field.data) {
+				if trackField.tag != "ptrk" {
+					continue
+				}
+				path := filepath.Join(c.root, filepath.FromSlash(seratoString(trackField.data)))
+				if track, ok := c.byPath[path]; ok {
+					tracks = append(tracks, track)
+				}
+			}
+		}
+	}
+
+	return models.NewInMemoryCollection(tracks...).Filter(func(i interfaces.Item) bool {
+		return strings.Compare(i.GetDateAdded(), c.args.From) > 0
+	}), nil
+}
+
+// findCrate accepts either a full "Parent%%Child" crate name or just the
+// name of the innermost crate.
+func (c *SeratoClient) findCrate(name string) string {
+	for _, crate := range c.crates {
+		if crate == name {
+			return crate
+		}
+	}
+	for _, crate := range c.crates {
+		parts := strings.Split(crate, seratoCrateSeparator)
+		if parts[len(parts)-1] == name {
+			return crate
+		}
+	}
+	return ""
+}
+
+func (c *SeratoClient) GetPlaylists(ctx context.Context) ([]*interfaces.PlaylistNode, error) {
+	playlistMap := make(map[string]*interfaces.PlaylistNode)
+	var roots []*interfaces.PlaylistNode
+
+	for _, crate := range c.crates {
+		parts := strings.Split(crate, seratoCrateSeparator)
+		var parent *interfaces.PlaylistNode
+		for i, part := range parts {
+			id := strings.Join(parts[:i+1], seratoCrateSeparator)
+			node, ok := playlistMap[id]
+			if !ok {
+				node = &interfaces.PlaylistNode{
+					ID:       id,
+					Name:     part,
+					Children: []*interfaces.PlaylistNode{},
+				}
+				playlistMap[id] = node
+				if parent == nil {
+					roots = append(roots, node)
+				} else {
+					parent.Children = append(parent.Children, node)
+				}
+			}
+			parent = node
+		}
+	}
+
+	return roots, nil
+}
+
+// GetNowPlaying uses the last track of the newest session in Serato's history.
+func (c *SeratoClient) GetNowPlaying(ctx context.Context) (interfaces.Item, error) {
+	sessions, _ := filepath.Glob(filepath.Join(c.seratoDir, "History", "Sessions", "*.session"))
+	if len(sessions) == 0 {
+		return nil, nil
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		a, _ := os.Stat(sessions[i])
+		b, _ := os.Stat(sessions[j])
+		return a != nil && b != nil && a.ModTime().Before(b.ModTime())
+	})
+
+	data, err := os.ReadFile(sessions[len(sessions)-1])
+	if err != nil {
+		return nil, err
+	}
+
+	var path string
+	for _, field := range readSeratoFields(data) {
+		if field.tag != "oent" {
+			continue
+		}
+		for _, entry := range readSeratoFields(field.data) {
+			if entry.tag != "adat" {
+				continue
+			}
+			// "adat" records use numeric tags instead of names
+			for _, adat := range readSeratoFields(entry.data) {
+				if binary.BigEndian.Uint32([]byte(adat.tag)) == seratoSessionPath {
+					path = filepath.Clean(seratoString(adat.data))
+				}
+			}
+		}
+	}
+
+	if path == "" {
+		return nil, nil
+	}
+
+	track, ok := c.byPath[path]
+	if !ok {
+		return nil, &interfaces.TrackNotFoundError{Pattern: path}
+	}
+	return track, nil
+}
+
+func (c *SeratoClient) Supports(capability interfaces.Capability) bool {
+	return seratoBackend.Supports(capability)
+}
+
+func (c *SeratoClient) Close() error {
+	return nil
+}
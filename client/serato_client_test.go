@@ -0,0 +1,225 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+	"unicode/utf16"
+
+	"github.com/xdave/keyid/args"
+)
+
+// seratoRecord builds a tag-length-value record around its contents.
+func seratoRecord(tag string, contents ...[]byte) []byte {
+	data := bytes.Join(contents, nil)
+	return append(binary.BigEndian.AppendUint32([]byte(tag), uint32(len(data))), data...)
+}
+
+// seratoText spells text in UTF-16BE.
+func seratoText(text string) []byte {
+	data := []byte{}
+	for _, unit := range utf16.Encode([]rune(text)) {
+		data = binary.BigEndian.AppendUint16(data, unit)
+	}
+	return data
+}
+
+func TestReadSeratoFields(t *testing.T) {
+	version := seratoRecord("vrsn", seratoText("2.0/Serato Scratch LIVE Database"))
+	data := append(slices.Clone(version), seratoRecord("otrk", seratoRecord("tsng", seratoText("Title")))...)
+	tests := []struct {
+		name string
+		data []byte
+		want []string
+	}{
+		{"records", data, []string{"vrsn", "otrk"}},
+		{"empty record", seratoRecord("tkey"), []string{"tkey"}},
+		{"truncated header", append(slices.Clone(version), "otrk\x00\x00"...), []string{"vrsn"}},
+		{"length past the data", append(seratoRecord("tsng", seratoText("A")), []byte("tart\xff\xff\xff\xffArtist")...), []string{"tsng"}},
+		{"nothing", nil, []string{}},
+	}
+	for _, tt := range tests {
+		tags := []string{}
+		for _, field := range readSeratoFields(tt.data) {
+			tags = append(tags, field.tag)
+		}
+		if !slices.Equal(tags, tt.want) {
+			t.Errorf("%s: readSeratoFields() = %v, want %v", tt.name, tags, tt.want)
+		}
+	}
+}
+
+func TestSeratoString(t *testing.T) {
+	tests := []struct {
+		data []byte
+		want string
+	}{
+		{seratoText("Café"), "Café"},
+		{seratoText("F#m"), "F#m"},
+		// A surrogate pair, and the NULs Serato pads some strings with
+		{seratoText("Sigur Rós 𝄞\x00\x00"), "Sigur Rós 𝄞"},
+		// An odd byte is dropped
+		{append(seratoText("8A"), 0), "8A"},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		if got := seratoString(tt.data); got != tt.want {
+			t.Errorf("seratoString(% x) = %q, want %q", tt.data, got, tt.want)
+		}
+	}
+}
+
+// Serato paths are relative to the root of the drive _Serato_ is on, which is
+// the filesystem root for the library in the home directory.
+func TestSeratoRoot(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	tests := []struct {
+		seratoDir string
+		want      string
+	}{
+		{filepath.Join(home, "Music", "_Serato_"), string(filepath.Separator)},
+		{filepath.Join("/Volumes", "USB", "_Serato_"), filepath.Join("/Volumes", "USB")},
+	}
+	for _, tt := range tests {
+		if got := seratoRoot(tt.seratoDir); got != tt.want {
+			t.Errorf("seratoRoot(%s) = %s, want %s", tt.seratoDir, got, tt.want)
+		}
+	}
+}
+
+func seratoTrackRecord(path string, title string, key string, added uint32) []byte {
+	return seratoRecord("otrk",
+		seratoRecord("ttyp", seratoText("mp3")),
+		seratoRecord("pfil", seratoText(path)),
+		seratoRecord("tsng", seratoText(title)),
+		seratoRecord("tart", seratoText("Artist")),
+		seratoRecord("tbpm", seratoText("124.00")),
+		seratoRecord("tkey", seratoText(key)),
+		seratoRecord("tcom", seratoText("8A - Energy 6")),
+		seratoRecord("uadd", binary.BigEndian.AppendUint32(nil, added)),
+	)
+}
+
+func seratoCrate(paths ...string) []byte {
+	data := seratoRecord("vrsn", seratoText("1.0/Serato ScratchLive Crate"))
+	for _, path := range paths {
+		data = append(data, seratoRecord("otrk", seratoRecord("ptrk", seratoText(path)))...)
+	}
+	return data
+}
+
+func writeSeratoFile(t *testing.T, path string, data []byte) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// newSeratoFixture writes a _Serato_ folder on a drive of its own, so track
+// paths are relative to the folder above it.
+func newSeratoFixture(t *testing.T) *SeratoClient {
+	drive := t.TempDir()
+	seratoDir := filepath.Join(drive, "_Serato_")
+	added := uint32(time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC).Unix())
+	writeSeratoFile(t, filepath.Join(seratoDir, "database V2"), bytes.Join([][]byte{
+		seratoRecord("vrsn", seratoText("2.0/Serato Scratch LIVE Database")),
+		seratoTrackRecord("Music/a.mp3", "A", "Am", added),
+		seratoTrackRecord("Music/Björk/b.mp3", "B", "8B", added),
+		seratoTrackRecord("Music/c.mp3", "C", "", added),
+	}, nil))
+	writeSeratoFile(t, filepath.Join(seratoDir, "Subcrates", "House.crate"), seratoCrate("Music/Björk/b.mp3", "Music/missing.mp3", "Music/a.mp3"))
+	writeSeratoFile(t, filepath.Join(seratoDir, "Subcrates", "House%%Deep.crate"), seratoCrate("Music/c.mp3"))
+	writeSeratoFile(t, filepath.Join(seratoDir, "Subcrates", "Techno.crate"), seratoCrate())
+
+	result, err := NewSeratoClient(LibraryParams{Args: &args.Args{Serato: seratoDir, From: "1970-01-01"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result.Library.(*SeratoClient)
+}
+
+func TestSeratoTracks(t *testing.T) {
+	client := newSeratoFixture(t)
+	root := filepath.Dir(client.seratoDir)
+	track := client.byPath[filepath.Join(root, "Music", "Björk", "b.mp3")].(*Track)
+	if track.Title != "B" || track.Artist != "Artist" || track.BPM != 124 || track.Scale.String() != "8B" ||
+		track.Energy != 6 || track.DateAdded != "2024-01-31" {
+		t.Errorf("track = %+v", track)
+	}
+	if key := client.byPath[filepath.Join(root, "Music", "a.mp3")].GetScale().String(); key != "8A" {
+		t.Errorf("Am = %s, want 8A", key)
+	}
+	if known := client.byPath[filepath.Join(root, "Music", "c.mp3")].GetScale().IsKnown(); known {
+		t.Error("a track without tkey has a key")
+	}
+}
+
+// Crates list tracks by the same relative path as the database, and can be
+// loaded by their full "Parent%%Child" name or the innermost one.
+func TestSeratoCrates(t *testing.T) {
+	client := newSeratoFixture(t)
+	ctx := context.Background()
+	tests := []struct {
+		crate string
+		want  []string
+	}{
+		{"House", []string{"B", "A"}},
+		{"House%%Deep", []string{"C"}},
+		{"Deep", []string{"C"}},
+		{"Techno", []string{}},
+		{"", []string{"A", "B", "C"}},
+	}
+	for _, tt := range tests {
+		collection, err := client.LoadPlaylist(ctx, tt.crate)
+		if err != nil {
+			t.Fatal(err)
+		}
+		titles := []string{}
+		for _, item := range collection.Items() {
+			titles = append(titles, item.GetTitle())
+		}
+		if !slices.Equal(titles, tt.want) {
+			t.Errorf("LoadPlaylist(%q) = %v, want %v", tt.crate, titles, tt.want)
+		}
+	}
+	if _, err := client.LoadPlaylist(ctx, "Missing"); err == nil {
+		t.Error("LoadPlaylist(\"Missing\") didn't fail")
+	}
+
+	playlists, err := client.GetPlaylists(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(playlists) != 2 || playlists[0].Name != "House" || len(playlists[0].Children) != 1 ||
+		playlists[0].Children[0].ID != "House%%Deep" || playlists[1].Name != "Techno" {
+		t.Errorf("GetPlaylists() = %+v", playlists)
+	}
+}
+
+// Now playing is the full path in the last entry of the newest session.
+func TestSeratoNowPlaying(t *testing.T) {
+	client := newSeratoFixture(t)
+	root := filepath.Dir(client.seratoDir)
+	entry := func(path string) []byte {
+		return seratoRecord("oent", seratoRecord("adat",
+			seratoRecord("\x00\x00\x00\x01", binary.BigEndian.AppendUint32(nil, 1)),
+			seratoRecord("\x00\x00\x00\x02", seratoText(path+"\x00")),
+		))
+	}
+	writeSeratoFile(t, filepath.Join(client.seratoDir, "History", "Sessions", "1.session"), append(
+		entry(filepath.Join(root, "Music", "a.mp3")),
+		entry(filepath.Join(root, "Music", "Björk", "b.mp3"))...,
+	))
+	track, err := client.GetNowPlaying(context.Background())
+	if err != nil || track == nil || track.GetTitle() != "B" {
+		t.Errorf("GetNowPlaying() = %v, %v, want B", track, err)
+	}
+}
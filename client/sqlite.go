@@ -0,0 +1,40 @@
+package client
+
+import (
+	"database/sql"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/xdave/keyid/interfaces"
+
+	_ "github.com/xeodou/go-sqlcipher"
+)
+
+// openReadOnlyDatabase opens a plain SQLite database read-only, so it is safe
+// to use while the DJ software that owns it is running.
+func openReadOnlyDatabase(path string) (*sql.DB, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	dsn := (&url.URL{Scheme: "file", OmitHost: true, Path: path, RawQuery: "mode=ro"}).String()
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, databaseError(path, err)
+	}
+	return db, nil
+}
+
+// databaseError reports SQLite's busy and locked errors ("database is
+// locked", "database table is locked") as a DatabaseLockedError and passes
+// any other error through.
+func databaseError(path string, err error) error {
+	if err != nil && strings.Contains(err.Error(), "is locked") {
+		return &interfaces.DatabaseLockedError{Path: path, Err: err}
+	}
+	return err
+}
@@ -2,13 +2,11 @@ package client
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"math"
-	"runtime/debug"
 
 	"github.com/xdave/keyid/interfaces"
-	"github.com/xdave/keyid/models"
-	"github.com/xdave/keyid/util"
 
 	"github.com/dvcrn/go-rekordbox/rekordbox"
 )
@@ -25,53 +23,43 @@ type Track struct {
 	Tags      []string
 }
 
-func NewTrackFromContent(client *rekordbox.Client, content *rekordbox.DjmdContent) interfaces.Item {
-	defer func() {
-		if r := recover(); r != nil {
-			fmt.Println(r)
-			fmt.Println("Could not get item", string(debug.Stack()))
-		}
-	}()
-	bpm := float64(content.BPM.Int64Value()) / 100.0
-	key, _ := client.DjmdKeyByID(context.Background(), content.KeyID)
-	var scaleName string
-	if key == nil {
-		fmt.Println("Track has no scale", content.FileNameL)
-		scaleName = "0A"
-	} else {
-		scaleName = key.ScaleName.String()
+// NewTrackFromContent looks up the key, artist and tags of a single rekordbox
+// track. Use a rekordboxLookup to build many tracks at once.
+func NewTrackFromContent(ctx context.Context, client *rekordbox.Client, content *rekordbox.DjmdContent) (interfaces.Item, error) {
+	lookup := newRekordboxLookup()
+
+	key, err := client.DjmdKeyByID(ctx, content.KeyID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
 	}
-	camelotKey := models.NewKey(scaleName)
-	artist, _ := client.DjmdArtistByID(context.Background(), content.ArtistID)
-	myTags, _ := client.DjmdSongMyTagByContentID(context.Background(), content.ID)
-
-	var artistName string
-	if artist == nil {
-		artistName = "<none>"
-	} else {
-		artistName = artist.Name.String()
+	if key != nil {
+		lookup.scales[content.KeyID.StringValue()] = key.ScaleName.String()
 	}
 
-	tags := []string{}
+	artist, err := client.DjmdArtistByID(ctx, content.ArtistID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if artist != nil {
+		lookup.artists[content.ArtistID.StringValue()] = artist.Name.String()
+	}
 
+	myTags, err := client.DjmdSongMyTagByContentID(ctx, content.ID)
+	if err != nil {
+		return nil, err
+	}
 	for _, t := range myTags {
-		tag, _ := client.DjmdMyTagByID(context.Background(), t.MyTagID)
+		tag, err := client.DjmdMyTagByID(ctx, t.MyTagID)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, err
+		}
 		if tag != nil {
-			tags = append(tags, tag.Name.String())
+			contentID := content.ID.StringValue()
+			lookup.tags[contentID] = append(lookup.tags[contentID], tag.Name.String())
 		}
 	}
 
-	return &Track{
-		ID:        content.ID.String(),
-		BPM:       bpm,
-		Scale:     camelotKey,
-		Artist:    artistName,
-		Title:     content.Title.String(),
-		Energy:    util.ParseEnergy(content.Commnt.String()),
-		Path:      content.FolderPath.String(),
-		DateAdded: content.DateCreated.String(),
-		Tags:      tags,
-	}
+	return lookup.newTrack(content), nil
 }
 
 func (t *Track) GetID() string {
@@ -114,22 +102,20 @@ func (track *Track) String() string {
 	)
 }
 
-func (track *Track) BpmMatchesTarget(targetBpm float64) bool {
+// BpmMatchesTarget tells if the track is within the tempo tolerance of
+// targetBpm.
+func (track *Track) BpmMatchesTarget(targetBpm float64, tempo interfaces.TempoOptions) bool {
 	diff := track.BPM - targetBpm
 	percent := diff / targetBpm * 100.0
-	// return math.Abs(percent) <= 7
-	// return math.Abs(percent) <= 2.5
-	return math.Abs(percent) <= 1.8
-	// return math.Abs(percent) <= 0.9
-}
-
-func (track *Track) IsCompatible(other interfaces.Item) bool {
-	return track.BpmMatchesTarget(other.GetBPM()) && other.GetScale().IsCompatible(track.GetScale())
+	return math.Abs(percent) <= tempo.Tolerance
 }
 
-func (track *Track) AsBpm(targetBpm float64) interfaces.Item {
-	diff := track.BPM - targetBpm
-	percent := diff / targetBpm * 100.0
+// AsBpm plays the track as close to targetBpm as the pitch range goes.
+// Without key lock its key moves by the pitch shift rounded to the nearest
+// semitone, 7 steps around the Camelot wheel each.
+func (track *Track) AsBpm(targetBpm float64, tempo interfaces.TempoOptions) interfaces.Item {
+	minBpm := track.BPM * (1 - tempo.PitchRange/100)
+	maxBpm := track.BPM * (1 + tempo.PitchRange/100)
 
 	newTrack := &Track{
 		ID:        track.ID,
@@ -142,13 +128,10 @@ func (track *Track) AsBpm(targetBpm float64) interfaces.Item {
 		Tags:      track.Tags,
 	}
 
-	if math.Abs(percent) > 5.0 && math.Abs(percent) < 6.5 {
-		newTrack.BPM = targetBpm
-		if targetBpm > track.BPM {
-			newTrack.Scale = newTrack.Scale.ChangeIndex(7)
-		} else {
-			newTrack.Scale = newTrack.Scale.ChangeIndex(-7)
-		}
+	newTrack.BPM = math.Min(math.Max(targetBpm, minBpm), maxBpm)
+	semitones := int(math.Round(interfaces.PitchShift(track.BPM, newTrack.BPM)))
+	if !tempo.KeyLock && semitones != 0 {
+		newTrack.Scale = newTrack.Scale.ChangeIndex(7 * semitones)
 	}
 
 	return newTrack
@@ -0,0 +1,192 @@
+package client
+
+import (
+	"crypto/sha1"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/xdave/keyid/args"
+	"github.com/xdave/keyid/interfaces"
+	"github.com/xdave/keyid/models"
+)
+
+// Bump when the cached fields or the way tracks are built from a source
+// change, so older cache files are ignored instead of misread.
+const trackCacheVersion = 3
+
+// cachedTrack holds the Track fields LoadPlaylist produces, along with the
+// version of the source row they were built from.
+type cachedTrack struct {
+	Version   string
+	ID        string
+	BPM       float64
+	Scale     string
+	Artist    string
+	Title     string
+	Energy    int
+	Path      string
+	DateAdded string
+	Tags      []string
+}
+
+type trackCacheFile struct {
+	Version int
+	// Stamp covers whatever every track depends on, like rekordbox's key and
+	// artist tables, and drops the whole cache when it changes.
+	Stamp  string
+	Tracks map[string]*cachedTrack
+}
+
+// trackCache keeps the tracks of a library in a gob file under the user's
+// cache folder between runs, so a source only has to read the tracks that
+// changed since the last run. A missing or unreadable cache is just empty.
+type trackCache struct {
+	path  string
+	file  *trackCacheFile
+	dirty bool
+}
+
+// openTrackCache opens the cache of the library at location. The cache is nil
+// with -noCache, and a nil cache never hits and never saves.
+func openTrackCache(a *args.Args, source interfaces.Source, location string) *trackCache {
+	if a.NoCache {
+		return nil
+	}
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return nil
+	}
+	hash := sha1.Sum([]byte(location))
+	cache := &trackCache{
+		path: filepath.Join(cacheDir, "keyid", source+"-"+hex.EncodeToString(hash[:8])+".gob"),
+		file: &trackCacheFile{Version: trackCacheVersion, Tracks: make(map[string]*cachedTrack)},
+	}
+
+	f, err := os.Open(cache.path)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			log.Default().Printf("cannot read track cache: %v", err)
+		}
+		return cache
+	}
+	defer f.Close()
+
+	file := &trackCacheFile{}
+	if err := gob.NewDecoder(f).Decode(file); err != nil {
+		log.Default().Printf("ignoring track cache '%s': %v", cache.path, err)
+		return cache
+	}
+	if file.Version == trackCacheVersion && file.Tracks != nil {
+		cache.file = file
+	}
+	return cache
+}
+
+// reset drops every track when the stamp differs from the cached one.
+func (c *trackCache) reset(stamp string) {
+	if c == nil || c.file.Stamp == stamp {
+		return
+	}
+	c.file.Stamp = stamp
+	c.file.Tracks = make(map[string]*cachedTrack)
+	c.dirty = true
+}
+
+// get returns the cached track when it was built from the same version of
+// the source row.
+func (c *trackCache) get(id string, version string) (interfaces.Item, bool) {
+	if c == nil {
+		return nil, false
+	}
+	cached, ok := c.file.Tracks[id]
+	if !ok || cached.Version != version {
+		return nil, false
+	}
+	return &Track{
+		ID:        cached.ID,
+		BPM:       cached.BPM,
+		Scale:     models.NewKey(cached.Scale),
+		Artist:    cached.Artist,
+		Title:     cached.Title,
+		Energy:    cached.Energy,
+		Path:      cached.Path,
+		DateAdded: cached.DateAdded,
+		Tags:      append([]string{}, cached.Tags...),
+	}, true
+}
+
+func (c *trackCache) put(id string, version string, track interfaces.Item) {
+	if c == nil {
+		return
+	}
+	c.file.Tracks[id] = &cachedTrack{
+		Version:   version,
+		ID:        track.GetID(),
+		BPM:       track.GetBPM(),
+		Scale:     scaleText(track.GetScale()),
+		Artist:    track.GetArtist(),
+		Title:     track.GetTitle(),
+		Energy:    track.GetEnergy(),
+		Path:      track.GetPath(),
+		DateAdded: track.GetDateAdded(),
+		Tags:      track.GetTags(),
+	}
+	c.dirty = true
+}
+
+// scaleText is what NewKey reads back into the same scale, unknown keys keep
+// what the source wrote so the key report can still show it.
+func scaleText(scale interfaces.Scale) string {
+	if unknown, ok := scale.(*models.UnknownScale); ok {
+		return unknown.Raw
+	}
+	return scale.String()
+}
+
+// prune drops the tracks that are no longer in the library.
+func (c *trackCache) prune(exists func(id string) bool) {
+	if c == nil {
+		return
+	}
+	for id := range c.file.Tracks {
+		if !exists(id) {
+			delete(c.file.Tracks, id)
+			c.dirty = true
+		}
+	}
+}
+
+// save writes the cache when it changed. It writes a temporary file first,
+// so an interrupted run never leaves a truncated cache behind.
+func (c *trackCache) save() error {
+	if c == nil || !c.dirty {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*")
+	if err != nil {
+		return err
+	}
+	if err := gob.NewEncoder(f).Encode(c.file); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	if err := os.Rename(f.Name(), c.path); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	c.dirty = false
+	return nil
+}
@@ -0,0 +1,302 @@
+package client
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xdave/keyid/args"
+	"github.com/xdave/keyid/interfaces"
+	"github.com/xdave/keyid/models"
+	"github.com/xdave/keyid/util"
+)
+
+const (
+	traktorFolder   = "FOLDER"
+	traktorPlaylist = "PLAYLIST"
+)
+
+type traktorNml struct {
+	Collection struct {
+		Entries []*traktorEntry `xml:"ENTRY"`
+	} `xml:"COLLECTION"`
+	Playlists struct {
+		Root *traktorNode `xml:"NODE"`
+	} `xml:"PLAYLISTS"`
+}
+
+type traktorEntry struct {
+	Title    string `xml:"TITLE,attr"`
+	Artist   string `xml:"ARTIST,attr"`
+	Location struct {
+		Dir    string `xml:"DIR,attr"`
+		File   string `xml:"FILE,attr"`
+		Volume string `xml:"VOLUME,attr"`
+	} `xml:"LOCATION"`
+	Info struct {
+		Comment    string `xml:"COMMENT,attr"`
+		Key        string `xml:"KEY,attr"`
+		ImportDate string `xml:"IMPORT_DATE,attr"`
+	} `xml:"INFO"`
+	Tempo struct {
+		BPM string `xml:"BPM,attr"`
+	} `xml:"TEMPO"`
+	MusicalKey *struct {
+		Value string `xml:"VALUE,attr"`
+	} `xml:"MUSICAL_KEY"`
+}
+
+type traktorNode struct {
+	Type     string         `xml:"TYPE,attr"`
+	Name     string         `xml:"NAME,attr"`
+	Children []*traktorNode `xml:"SUBNODES>NODE"`
+	Entries  []struct {
+		PrimaryKey struct {
+			Key string `xml:"KEY,attr"`
+		} `xml:"PRIMARYKEY"`
+	} `xml:"PLAYLIST>ENTRY"`
+}
+
+// key returns the "VOLUME/:DIR/:FILE" string Traktor playlists use to refer to an entry.
+func (e *traktorEntry) key() string {
+	return e.Location.Volume + e.Location.Dir + e.Location.File
+}
+
+// path converts Traktor's "/:"-separated location into a file path.
+func (e *traktorEntry) path() string {
+	dir := strings.ReplaceAll(e.Location.Dir, "/:", "/")
+	if strings.HasSuffix(e.Location.Volume, ":") {
+		// Windows volumes are drive letters, e.g. "C:"
+		return e.Location.Volume + dir + e.Location.File
+	}
+	return dir + e.Location.File
+}
+
+// TraktorClient reads tracks and playlists from a Traktor collection.nml.
+type TraktorClient struct {
+	args    *args.Args
+	nmlPath string
+	library *traktorNml
+	tracks  []interfaces.Item
+	byKey   map[string]interfaces.Item
+}
+
+var traktorBackend = &Backend{
+	Name: interfaces.SourceTraktor,
+	Capabilities: []interfaces.Capability{
+		interfaces.CapabilityNowPlaying,
+		interfaces.CapabilityPlaylistTree,
+	},
+	New: NewTraktorClient,
+}
+
+func NewTraktorBackend() BackendResult {
+	return BackendResult{Backend: traktorBackend}
+}
+
+func NewTraktorClient(params LibraryParams) (LibraryResult, error) {
+	if params.Args.NML == "" {
+		return LibraryResult{}, errors.New("the 'traktor' source needs the path of a collection.nml (-nml)")
+	}
+
+	library, err := readTraktorNml(params.Args.NML)
+	if err != nil {
+		return LibraryResult{}, err
+	}
+
+	traktorClient := &TraktorClient{
+		args:    params.Args,
+		nmlPath: params.Args.NML,
+		library: library,
+		byKey:   make(map[string]interfaces.Item),
+	}
+
+	for _, entry := range library.Collection.Entries {
+		track := NewTrackFromNml(entry)
+		traktorClient.tracks = append(traktorClient.tracks, track)
+		traktorClient.byKey[entry.key()] = track
+	}
+
+	return LibraryResult{
+		Library: traktorClient,
+	}, nil
+}
+
+func readTraktorNml(path string) (*traktorNml, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	library := &traktorNml{}
+	if err := xml.Unmarshal(data, library); err != nil {
+		return nil, err
+	}
+	return library, nil
+}
+
+func NewTrackFromNml(entry *traktorEntry) interfaces.Item {
+	bpm, _ := strconv.ParseFloat(entry.Tempo.BPM, 64)
+
+	scaleName := entry.Info.Key
+	if entry.MusicalKey != nil {
+		value, err := strconv.Atoi(entry.MusicalKey.Value)
+		if camelot, ok := models.TraktorKeyToCamelot[value]; err == nil && ok {
+			scaleName = camelot
+		}
+	}
+
+	artistName := entry.Artist
+	if artistName == "" {
+		artistName = "<none>"
+	}
+
+	// Traktor writes dates as "2024/1/31"
+	dateAdded := entry.Info.ImportDate
+	if date, err := time.Parse("2006/1/2", dateAdded); err == nil {
+		dateAdded = date.Format(time.DateOnly)
+	}
+
+	return &Track{
+		ID:        entry.key(),
+		BPM:       bpm,
+		Scale:     models.NewKey(scaleName),
+		Artist:    artistName,
+		Title:     entry.Title,
+		Energy:    util.ParseEnergy(entry.Info.Comment),
+		Path:      entry.path(),
+		DateAdded: dateAdded,
+		Tags:      []string{},
+	}
+}
+
+func (c *TraktorClient) LoadPlaylist(ctx context.Context, name string) (interfaces.Collection, error) {
+	tracks := []interfaces.Item{}
+
+	if name == "" {
+		tracks = append(tracks, c.tracks...)
+	} else {
+		playlist := c.findPlaylist(c.library.Playlists.Root, name)
+		if playlist == nil {
+			return nil, &interfaces.PlaylistNotFoundError{Name: name}
+		}
+		for _, entry := range playlist.Entries {
+			if track, ok := c.byKey[entry.PrimaryKey.Key]; ok {
+				tracks = append(tracks, track)
+			}
+		}
+	}
+
+	return models.NewInMemoryCollection(tracks...).Filter(func(i interfaces.Item) bool {
+		return strings.Compare(i.GetDateAdded(), c.args.From) > 0
+	}), nil
+}
+
+func (c *TraktorClient) findPlaylist(node *traktorNode, name string) *traktorNode {
+	if node == nil {
+		return nil
+	}
+	if node.Type == traktorPlaylist && node.Name == name {
+		return node
+	}
+	for _, child := range node.Children {
+		if found := c.findPlaylist(child, name); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func (c *TraktorClient) firstPlaylist(node *traktorNode) *traktorNode {
+	if node == nil {
+		return nil
+	}
+	if node.Type == traktorPlaylist {
+		return node
+	}
+	for _, child := range node.Children {
+		if found := c.firstPlaylist(child); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func (c *TraktorClient) GetPlaylists(ctx context.Context) ([]*interfaces.PlaylistNode, error) {
+	root := c.library.Playlists.Root
+	if root == nil {
+		return nil, nil
+	}
+	return c.buildPlaylistNodes(root.Children, ""), nil
+}
+
+// buildPlaylistNodes converts the NML NODE tree, using each node's position in
+// the tree as its ID.
+func (c *TraktorClient) buildPlaylistNodes(nodes []*traktorNode, parentID string) []*interfaces.PlaylistNode {
+	playlists := []*interfaces.PlaylistNode{}
+	for i, node := range nodes {
+		if node.Type != traktorFolder && node.Type != traktorPlaylist {
+			// Smartlists and other node types can't be loaded by name
+			continue
+		}
+		id := strconv.Itoa(i)
+		if parentID != "" {
+			id = parentID + "." + id
+		}
+		playlists = append(playlists, &interfaces.PlaylistNode{
+			ID:       id,
+			Name:     node.Name,
+			Children: c.buildPlaylistNodes(node.Children, id),
+		})
+	}
+	return playlists
+}
+
+// GetNowPlaying uses the last track of the newest history file Traktor keeps
+// in the "History" folder next to the collection.
+func (c *TraktorClient) GetNowPlaying(ctx context.Context) (interfaces.Item, error) {
+	historyFiles, _ := filepath.Glob(filepath.Join(filepath.Dir(c.nmlPath), "History", "*.nml"))
+	if len(historyFiles) == 0 {
+		return nil, nil
+	}
+	sort.Slice(historyFiles, func(i, j int) bool {
+		a, _ := os.Stat(historyFiles[i])
+		b, _ := os.Stat(historyFiles[j])
+		return a != nil && b != nil && a.ModTime().Before(b.ModTime())
+	})
+
+	history, err := readTraktorNml(historyFiles[len(historyFiles)-1])
+	if err != nil {
+		return nil, err
+	}
+
+	// The history playlist is in play order, the collection section is not
+	var key string
+	if playlist := c.firstPlaylist(history.Playlists.Root); playlist != nil && len(playlist.Entries) > 0 {
+		key = playlist.Entries[len(playlist.Entries)-1].PrimaryKey.Key
+	} else if entries := history.Collection.Entries; len(entries) > 0 {
+		key = entries[len(entries)-1].key()
+	} else {
+		return nil, nil
+	}
+
+	track, ok := c.byKey[key]
+	if !ok {
+		return nil, &interfaces.TrackNotFoundError{Pattern: key}
+	}
+	return track, nil
+}
+
+func (c *TraktorClient) Supports(capability interfaces.Capability) bool {
+	return traktorBackend.Supports(capability)
+}
+
+func (c *TraktorClient) Close() error {
+	return nil
+}
@@ -0,0 +1,197 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/xdave/keyid/args"
+	"github.com/xdave/keyid/interfaces"
+	"github.com/xdave/keyid/models"
+)
+
+// traktorKeys are the keys of MUSICAL_KEY 0 to 23, the majors from C and then
+// the minors from Cm.
+var traktorKeys = []string{"C", "C#", "D", "Eb", "E", "F", "F#", "G", "Ab", "A", "Bb", "B",
+	"Cm", "C#m", "Dm", "Ebm", "Em", "Fm", "F#m", "Gm", "G#m", "Am", "Bbm", "Bm"}
+
+func traktorEntryXML(volume string, file string, musicalKey string, infoKey string) string {
+	if musicalKey != "" {
+		musicalKey = fmt.Sprintf(`<MUSICAL_KEY VALUE=%q></MUSICAL_KEY>`, musicalKey)
+	}
+	return fmt.Sprintf(`<ENTRY TITLE=%q ARTIST="Artist">
+<LOCATION DIR="/:Users/:dj/:Music/:" FILE=%q VOLUME=%q></LOCATION>
+<INFO KEY=%q COMMENT="Energy 7" IMPORT_DATE="2024/1/31"></INFO>
+<TEMPO BPM="124.000061"></TEMPO>%s
+</ENTRY>`, file, file, volume, infoKey, musicalKey)
+}
+
+func traktorPrimaryKeys(files ...string) string {
+	entries := []string{}
+	for _, file := range files {
+		entries = append(entries, fmt.Sprintf(`<ENTRY><PRIMARYKEY TYPE="TRACK" KEY="Macintosh HD/:Users/:dj/:Music/:%s"></PRIMARYKEY></ENTRY>`, file))
+	}
+	return fmt.Sprintf(`<PLAYLIST ENTRIES="%d" TYPE="LIST">%s</PLAYLIST>`, len(files), strings.Join(entries, ""))
+}
+
+func writeTraktorNml(t *testing.T, path string, entries []string, playlists string) {
+	nml := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8" standalone="no" ?>
+<NML VERSION="19"><HEAD COMPANY="www.native-instruments.com" PROGRAM="Traktor"></HEAD>
+<COLLECTION ENTRIES="%d">%s</COLLECTION>
+<PLAYLISTS>%s</PLAYLISTS>
+</NML>`, len(entries), strings.Join(entries, "\n"), playlists)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(nml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// newTraktorFixture writes a collection.nml with a track for every
+// MUSICAL_KEY and a few without one, in nested playlists.
+func newTraktorFixture(t *testing.T) *TraktorClient {
+	entries := []string{}
+	for value := range traktorKeys {
+		// INFO KEY is what Traktor shows, MUSICAL_KEY wins over it
+		entries = append(entries, traktorEntryXML("Macintosh HD", fmt.Sprintf("%d.mp3", value), fmt.Sprint(value), "1d"))
+	}
+	entries = append(entries,
+		traktorEntryXML("Macintosh HD", "info.mp3", "", "8A"),
+		traktorEntryXML("Macintosh HD", "out-of-range.mp3", "24", "9A"),
+		traktorEntryXML("Macintosh HD", "none.mp3", "", ""),
+		traktorEntryXML("C:", "windows.mp3", "21", ""),
+	)
+	playlists := `<NODE TYPE="FOLDER" NAME="$ROOT"><SUBNODES COUNT="3">
+<NODE TYPE="FOLDER" NAME="Sets"><SUBNODES COUNT="1">
+<NODE TYPE="PLAYLIST" NAME="Friday">` + traktorPrimaryKeys("21.mp3", "missing.mp3", "0.mp3", "info.mp3") + `</NODE>
+</SUBNODES></NODE>
+<NODE TYPE="SMARTLIST" NAME="Recent"><SMARTLIST></SMARTLIST></NODE>
+<NODE TYPE="PLAYLIST" NAME="Empty">` + traktorPrimaryKeys() + `</NODE>
+</SUBNODES></NODE>`
+
+	dir := t.TempDir()
+	nmlPath := filepath.Join(dir, "collection.nml")
+	writeTraktorNml(t, nmlPath, entries, playlists)
+	result, err := NewTraktorClient(LibraryParams{Args: &args.Args{NML: nmlPath, From: "1970-01-01"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result.Library.(*TraktorClient)
+}
+
+func TestTraktorMusicalKey(t *testing.T) {
+	client := newTraktorFixture(t)
+	for value, name := range traktorKeys {
+		track := client.byKey[fmt.Sprintf("Macintosh HD/:Users/:dj/:Music/:%d.mp3", value)]
+		if want := models.NewKey(name); !track.GetScale().IsEqual(want) {
+			t.Errorf("MUSICAL_KEY %d = %v, want %v (%s)", value, track.GetScale(), want, name)
+		}
+	}
+	tests := []struct {
+		file string
+		want string
+	}{
+		{"info.mp3", "8A"},
+		{"out-of-range.mp3", "9A"},
+		{"none.mp3", "?"},
+	}
+	for _, tt := range tests {
+		track := client.byKey["Macintosh HD/:Users/:dj/:Music/:"+tt.file]
+		if got := track.GetScale().String(); got != tt.want {
+			t.Errorf("%s key = %s, want %s", tt.file, got, tt.want)
+		}
+	}
+}
+
+func TestTraktorTracks(t *testing.T) {
+	client := newTraktorFixture(t)
+	track := client.byKey["Macintosh HD/:Users/:dj/:Music/:0.mp3"].(*Track)
+	if track.Path != "/Users/dj/Music/0.mp3" || track.BPM != 124.000061 || track.Energy != 7 || track.DateAdded != "2024-01-31" {
+		t.Errorf("track = %+v", track)
+	}
+	windows := client.byKey["C:/:Users/:dj/:Music/:windows.mp3"].(*Track)
+	if windows.Path != "C:/Users/dj/Music/windows.mp3" || windows.Scale.String() != "8A" {
+		t.Errorf("windows track = %+v", windows)
+	}
+}
+
+// Playlists list their tracks by PRIMARYKEY, the volume, folder and file of
+// a collection entry, in play order.
+func TestTraktorPlaylists(t *testing.T) {
+	client := newTraktorFixture(t)
+	ctx := context.Background()
+	tests := []struct {
+		playlist string
+		want     []string
+	}{
+		{"Friday", []string{"21.mp3", "0.mp3", "info.mp3"}},
+		{"Empty", []string{}},
+	}
+	for _, tt := range tests {
+		collection, err := client.LoadPlaylist(ctx, tt.playlist)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files := []string{}
+		for _, item := range collection.Items() {
+			files = append(files, filepath.Base(item.GetPath()))
+		}
+		if !slices.Equal(files, tt.want) {
+			t.Errorf("LoadPlaylist(%q) = %v, want %v", tt.playlist, files, tt.want)
+		}
+	}
+	all, err := client.LoadPlaylist(ctx, "")
+	if err != nil || all.Len() != len(traktorKeys)+4 {
+		t.Errorf("LoadPlaylist(\"\") = %v tracks, %v", all.Len(), err)
+	}
+	for _, name := range []string{"Sets", "Recent", "Missing"} {
+		if _, err := client.LoadPlaylist(ctx, name); err == nil {
+			t.Errorf("LoadPlaylist(%q) didn't fail", name)
+		}
+	}
+
+	playlists, err := client.GetPlaylists(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, node := range playlists {
+		got = append(got, node.ID+" "+node.Name)
+		for _, child := range node.Children {
+			got = append(got, child.ID+" "+child.Name)
+		}
+	}
+	if want := []string{"0 Sets", "0.0 Friday", "2 Empty"}; !slices.Equal(got, want) {
+		t.Errorf("GetPlaylists() = %v, want %v", got, want)
+	}
+}
+
+// Now playing is the last track of the newest history playlist.
+func TestTraktorNowPlaying(t *testing.T) {
+	client := newTraktorFixture(t)
+	ctx := context.Background()
+	if track, err := client.GetNowPlaying(ctx); track != nil || err != nil {
+		t.Errorf("GetNowPlaying() without history = %v, %v", track, err)
+	}
+
+	history := filepath.Join(filepath.Dir(client.nmlPath), "History", "history_2024y01m31d_22h00m00s.nml")
+	writeTraktorNml(t, history, nil, `<NODE TYPE="FOLDER" NAME="$ROOT"><SUBNODES COUNT="1"><NODE TYPE="PLAYLIST" NAME="HistoryPlaylist">`+
+		traktorPrimaryKeys("3.mp3", "5.mp3")+`</NODE></SUBNODES></NODE>`)
+	track, err := client.GetNowPlaying(ctx)
+	if err != nil || track == nil || filepath.Base(track.GetPath()) != "5.mp3" {
+		t.Errorf("GetNowPlaying() = %v, %v, want 5.mp3", track, err)
+	}
+
+	writeTraktorNml(t, history, nil, `<NODE TYPE="FOLDER" NAME="$ROOT"><SUBNODES COUNT="1"><NODE TYPE="PLAYLIST" NAME="HistoryPlaylist">`+
+		traktorPrimaryKeys("gone.mp3")+`</NODE></SUBNODES></NODE>`)
+	var notFound *interfaces.TrackNotFoundError
+	if _, err := client.GetNowPlaying(ctx); !errors.As(err, &notFound) {
+		t.Errorf("GetNowPlaying() error = %v, want a TrackNotFoundError", err)
+	}
+}
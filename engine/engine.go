@@ -0,0 +1,495 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"github.com/xdave/keyid/args"
+	"github.com/xdave/keyid/interfaces"
+	"github.com/xdave/keyid/models"
+	"github.com/xdave/keyid/util"
+
+	"go.uber.org/fx"
+)
+
+// Engine holds the harmonic mixing rules. It works on any collection of
+// tracks and only asks the library for playlists and the track playing.
+type Engine struct {
+	args       *args.Args
+	history    *History
+	library    interfaces.Library
+	printer    interfaces.Printer
+	shutdowner fx.Shutdowner
+	// done is cancelled on shutdown, stopping running operations
+	done context.Context
+	// The GUI switches rule sets and tempo profiles while operations run
+	rules atomic.Pointer[models.RuleSet]
+	tempo atomic.Pointer[interfaces.TempoOptions]
+}
+
+type EngineParams struct {
+	fx.In
+	fx.Lifecycle
+	fx.Shutdowner
+	Args    *args.Args
+	History *History
+	Library interfaces.Library
+	Printer interfaces.Printer
+}
+
+type EngineResult struct {
+	fx.Out
+	Client interfaces.Client
+}
+
+func NewEngine(params EngineParams) (EngineResult, error) {
+	engine := New(params.Args, params.History, params.Library)
+	engine.printer = params.Printer
+	engine.shutdowner = params.Shutdowner
+	if err := engine.SetRules(params.Args.Rules); err != nil {
+		return EngineResult{}, err
+	}
+	if err := engine.SetTempo(params.Args.Tempo, params.Args.Tolerance); err != nil {
+		return EngineResult{}, err
+	}
+
+	done, cancel := context.WithCancel(context.Background())
+	engine.done = done
+	params.Lifecycle.Append(fx.Hook{
+		OnStop: func(ctx context.Context) error {
+			cancel()
+			return nil
+		},
+	})
+
+	return EngineResult{
+		Client: engine,
+	}, nil
+}
+
+// New creates an engine outside of fx, e.g. over an in-memory library.
+func New(args *args.Args, history *History, library interfaces.Library) *Engine {
+	if history == nil {
+		history = NewHistory(HistoryParams{}).History
+	}
+	engine := &Engine{
+		args:    args,
+		history: history,
+		library: library,
+		done:    context.Background(),
+	}
+	// An unknown -rules or -tempo falls back to the default here, NewEngine
+	// reports it
+	if engine.SetRules(args.Rules) != nil {
+		engine.rules.Store(models.KeyidRules)
+	}
+	if engine.SetTempo(args.Tempo, args.Tolerance) != nil {
+		engine.SetTempo(models.Pitch6.Name, models.DefaultTempoTolerance)
+	}
+	return engine
+}
+
+// withTimeout limits an operation to -timeout and cancels it on shutdown.
+func (c *Engine) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	var cancel context.CancelFunc
+	if c.args.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, c.args.Timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	stop := context.AfterFunc(c.done, cancel)
+	if c.done.Err() != nil {
+		// Already shutting down, AfterFunc would cancel too late
+		cancel()
+	}
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
+
+func (c *Engine) LoadPlaylist(ctx context.Context, name string) (interfaces.Collection, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	collection, err := c.library.LoadPlaylist(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	// Indexed once, so suggesting and generating don't scan every track, and
+	// safe to share, since the GUI asks for now playing from a goroutine
+	return models.NewSyncCollection(collection.Items()...), nil
+}
+
+func (c *Engine) GetPlaylists(ctx context.Context) ([]*interfaces.PlaylistNode, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	return c.library.GetPlaylists(ctx)
+}
+
+func (c *Engine) GetTrackByTitle(pattern string, from interfaces.Collection) (interfaces.Item, error) {
+	for _, track := range from.Items() {
+		if strings.Contains(strings.ToLower(track.GetTitle()), strings.ToLower(pattern)) {
+			return track, nil
+		}
+	}
+
+	return nil, &interfaces.TrackNotFoundError{Pattern: pattern}
+}
+
+func (c *Engine) GetCompatibleTracks(track interfaces.Item, from interfaces.Collection) interfaces.Collection {
+	rules := c.rules.Load()
+	tempo := *c.tempo.Load()
+	compat := models.NewIndexedCollection()
+
+	type scoredTrack = models.Pair[interfaces.Item, interfaces.Transition]
+	scored := []scoredTrack{}
+	for _, item := range candidates(track, from, rules, tempo, c.args.UnknownKeys) {
+		if !c.history.Contains(item) && !item.Equals(track) {
+			if transition := c.score(rules, track, item, tempo); transition.IsCompatible() {
+				scored = append(scored, scoredTrack{First: item, Second: transition})
+			}
+		}
+	}
+	// Best first, ties stay in collection order
+	ranked := models.NewCollection(scored...).SortWith(func(a, b scoredTrack) bool {
+		return a.Second.Score > b.Second.Score
+	})
+	ranked.ForEach(func(pair scoredTrack) {
+		compat.Add(models.NewSuggestion(pair.First, pair.Second))
+	})
+
+	// -tags keeps the tracks with one of its tags, then -excludeTags drops
+	// the ones with one of its own
+	var suggestions interfaces.Collection = compat
+	if tags := tagList(c.args.Tags); len(tags) > 0 {
+		suggestions = suggestions.Filter(func(item interfaces.Item) bool {
+			return util.StringSlice(item.GetTags()).ContainsAnyOf(tags)
+		})
+	}
+	if excludeTags := tagList(c.args.ExcludeTags); len(excludeTags) > 0 {
+		suggestions = suggestions.Filter(func(item interfaces.Item) bool {
+			return !util.StringSlice(item.GetTags()).ContainsAnyOf(excludeTags)
+		})
+	}
+	return suggestions
+}
+
+// tagList splits a comma separated tags flag, leaving out empty tags
+func tagList(flag string) []string {
+	tags := []string{}
+	for _, tag := range strings.Split(flag, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+func (c *Engine) Generate(ctx context.Context, collection interfaces.Collection) (interfaces.Collection, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	rules := c.rules.Load()
+	tempo := *c.tempo.Load()
+	crate := models.NewIndexedCollection(collection.Items()...)
+
+	if c.args.Random {
+		crate.RandomShuffle()
+	}
+
+	startWith, err := c.GetTrackByTitle(c.args.StartWith, crate)
+	if err != nil {
+		return nil, err
+	}
+	if !hasKey(startWith) && !c.args.UnknownKeys {
+		return nil, &interfaces.KeyMissingError{Track: startWith}
+	}
+	if !c.args.UnknownKeys {
+		// Not even as the tracks added when nothing mixes
+		crate = models.NewIndexedCollection(crate.Collection.Filter(hasKey).Items()...)
+	}
+
+	playlist := models.NewIndexedCollection(startWith)
+
+	var lastTrack interfaces.Item
+	retries := 10
+
+	for retries > 0 {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		lastTrack = playlist.Last()
+
+		compatible := c.GetCompatibleTracks(lastTrack, crate)
+		if c.args.Random {
+			compatible.RandomShuffle()
+		}
+
+		if playlist.Len() == crate.Len() {
+			break
+		}
+		hasCompatibleTrack := false
+		if compatible.IsEmpty() {
+			hasCompatibleTrack = false
+		}
+		for _, track := range compatible.Items() {
+			if playlist.Contains(track) {
+				continue
+			}
+			hasCompatibleTrack = true
+			playlist.Add(track)
+			if c.args.Random {
+				compatible.RandomShuffle()
+			}
+			break
+
+		}
+		if !hasCompatibleTrack {
+			// Nothing mixes: add the best scoring track left, in BPM range
+			// until half the retries are gone
+			type scoredTrack = models.Pair[interfaces.Item, interfaces.Transition]
+			scored := []scoredTrack{}
+			for _, track := range crate.Items() {
+				if !playlist.Contains(track) {
+					scored = append(scored, scoredTrack{First: track, Second: c.score(rules, lastTrack, track, tempo)})
+				}
+			}
+			ranked := models.NewCollection(scored...).SortWith(func(a, b scoredTrack) bool {
+				return a.Second.Score > b.Second.Score
+			})
+			for _, pair := range ranked.Items() {
+				if retries <= 5 || bpmMatches(lastTrack, pair.First, tempo) {
+					playlist.Add(models.NewSuggestion(pair.First, pair.Second))
+					break
+				}
+			}
+			retries -= 1
+			// break
+		}
+	}
+
+	return playlist, nil
+}
+
+// GetNowPlaying returns the -startWith track, or the track the library last
+// saw playing, which is added to the history.
+func (c *Engine) GetNowPlaying(ctx context.Context, collection interfaces.Collection) (interfaces.Item, error) {
+	if c.args.StartWith != "" {
+		return c.GetTrackByTitle(c.args.StartWith, collection)
+	}
+
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	track, err := c.library.GetNowPlaying(ctx)
+	if err != nil || track == nil {
+		return nil, err
+	}
+	c.history.Add(track)
+	return track, nil
+}
+
+// Suggest returns the tracks that mix with the one playing, which is empty
+// when the library doesn't know what is playing.
+func (c *Engine) Suggest(ctx context.Context, collection interfaces.Collection) (interfaces.Collection, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	track, err := c.GetNowPlaying(ctx, collection)
+	if err != nil {
+		return nil, err
+	}
+
+	if track == nil {
+		return models.NewInMemoryCollection(), nil
+	}
+	if !hasKey(track) && !c.args.UnknownKeys {
+		return nil, &interfaces.KeyMissingError{Track: track}
+	}
+
+	return c.GetCompatibleTracks(track, collection), nil
+}
+
+// GetUnknownKeys returns the tracks of the collection whose key is missing or
+// can't be read, which never mix unless -unknownKeys is set.
+func (c *Engine) GetUnknownKeys(collection interfaces.Collection) interfaces.Collection {
+	return collection.Filter(func(i interfaces.Item) bool {
+		return !hasKey(i)
+	})
+}
+
+// Run suggests, generates or lists the tracks without a key once and prints
+// the tracks, shutting down with the exit code of the error when that fails.
+func (c *Engine) Run() error {
+	err := c.run(context.Background())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		if c.shutdowner != nil {
+			c.shutdowner.Shutdown(fx.ExitCode(ExitCode(err)))
+		}
+	}
+	return err
+}
+
+func (c *Engine) run(ctx context.Context) error {
+	collection, err := c.LoadPlaylist(ctx, c.args.Playlist)
+	if err != nil {
+		return err
+	}
+
+	var tracks interfaces.Collection
+	if c.args.Mode == interfaces.ModeSuggest {
+		tracks, err = c.Suggest(ctx, collection)
+	} else if c.args.Mode == interfaces.ModeGenerate {
+		tracks, err = c.Generate(ctx, collection)
+	} else if c.args.Mode == interfaces.ModeKeys {
+		tracks = c.GetUnknownKeys(collection)
+		fmt.Fprintf(os.Stderr, "%d of %d tracks have no usable key\n", tracks.Len(), collection.Len())
+	}
+	if err != nil || tracks == nil || c.printer == nil {
+		return err
+	}
+
+	c.printer.PrintHeader()
+	tracks.ForEach(c.printer.Print)
+	return nil
+}
+
+// SetRules selects the harmonic rule set by preset name or as a list of key
+// moves, see models.ParseRuleSet.
+func (c *Engine) SetRules(name string) error {
+	rules, err := models.ParseRuleSet(name)
+	if err != nil {
+		return err
+	}
+	c.rules.Store(rules)
+	return nil
+}
+
+func (c *Engine) GetRules() string {
+	return c.rules.Load().Name
+}
+
+// SetTempo selects the pitch range by tempo profile name, see
+// models.TempoProfiles, and the BPM tolerance in percent. Key lock and the
+// tempo families stay as -keyLock, -halfTime and -threeFour set them.
+func (c *Engine) SetTempo(profile string, tolerance float64) error {
+	tempoProfile, err := models.ParseTempoProfile(profile)
+	if err != nil {
+		return err
+	}
+	tempo, err := tempoProfile.Options(tolerance)
+	if err != nil {
+		return err
+	}
+	tempo.KeyLock = c.args.KeyLock
+	tempo.HalfTime = c.args.HalfTime
+	tempo.ThreeFour = c.args.ThreeFour
+	c.tempo.Store(&tempo)
+	return nil
+}
+
+func (c *Engine) GetTempo() interfaces.TempoOptions {
+	return *c.tempo.Load()
+}
+
+func (c *Engine) Supports(capability interfaces.Capability) bool {
+	return c.library.Supports(capability)
+}
+
+func (c *Engine) Close() error {
+	return c.library.Close()
+}
+
+// ScoreTransition rates mixing from track into next with the selected rule
+// set, the zero Transition when they don't mix.
+func (c *Engine) ScoreTransition(track interfaces.Item, next interfaces.Item) interfaces.Transition {
+	return c.score(c.rules.Load(), track, next, *c.tempo.Load())
+}
+
+// score rates a transition with the rule set, or by tempo alone when a key is
+// unknown and -unknownKeys lets such tracks mix.
+func (c *Engine) score(rules *models.RuleSet, track interfaces.Item, next interfaces.Item, tempo interfaces.TempoOptions) interfaces.Transition {
+	if c.args.UnknownKeys && (!hasKey(track) || !hasKey(next)) {
+		return models.ScoreUnknownKey(track, next, tempo)
+	}
+	return rules.Score(track, next, tempo)
+}
+
+// candidates narrows an indexed collection down to the tracks that can score
+// above 0: the ones within the pitch range of track, or of each multiple of
+// its BPM the tempo options allow, whose key is compatible once AsBpm pitched
+// them, one query for each semitone they can move by. With unknownKeys the
+// tracks without a usable key in that range are candidates too, and every
+// track in it when track has no usable key itself.
+func candidates(track interfaces.Item, from interfaces.Collection, rules *models.RuleSet, tempo interfaces.TempoOptions, unknownKeys bool) []interfaces.Item {
+	index, ok := from.(interfaces.IndexedCollection)
+	if !ok {
+		return from.Items()
+	}
+	compatibleAfter := func(shift int) func(scale interfaces.Scale) bool {
+		return func(scale interfaces.Scale) bool {
+			if shift != 0 {
+				scale = scale.ChangeIndex(shift)
+			}
+			return rules.Allows(track.GetScale(), scale)
+		}
+	}
+
+	queries := []interfaces.CandidateQuery{}
+	pitchRange, tolerance := tempo.PitchRange/100, tempo.Tolerance/100
+	for _, multiplier := range tempo.Multipliers() {
+		bpm := track.GetBPM() / multiplier
+		// The tracks AsBpm brings within the tolerance of bpm, a bit wider
+		// so rounding never drops one
+		minBPM := bpm / (1 + tolerance) / (1 + pitchRange) * 0.999
+		maxBPM := bpm / (1 - tolerance) / (1 - pitchRange) * 1.001
+		if unknownKeys && !track.GetScale().IsKnown() {
+			queries = append(queries, interfaces.CandidateQuery{MinBPM: minBPM, MaxBPM: maxBPM, KeyFilter: func(scale interfaces.Scale) bool {
+				return true
+			}})
+			continue
+		}
+		if unknownKeys {
+			queries = append(queries, interfaces.CandidateQuery{MinBPM: minBPM, MaxBPM: maxBPM, KeyFilter: func(scale interfaces.Scale) bool {
+				return !scale.IsKnown()
+			}})
+		}
+		if tempo.KeyLock || pitchRange == 0 {
+			queries = append(queries, interfaces.CandidateQuery{MinBPM: minBPM, MaxBPM: maxBPM, KeyFilter: compatibleAfter(0)})
+			continue
+		}
+
+		maxShift := int(math.Ceil(-interfaces.PitchShift(1, 1-pitchRange)))
+		for semitones := -maxShift; semitones <= maxShift; semitones++ {
+			// The tracks AsBpm rounds to this many semitones up
+			low := math.Max(minBPM, bpm/(1+tolerance)/math.Exp2((float64(semitones)+0.5)/12)*0.999)
+			high := math.Min(maxBPM, bpm/(1-tolerance)/math.Exp2((float64(semitones)-0.5)/12)*1.001)
+			if low <= high {
+				queries = append(queries, interfaces.CandidateQuery{MinBPM: low, MaxBPM: high, KeyFilter: compatibleAfter(7 * semitones)})
+			}
+		}
+	}
+	return index.Candidates(queries...)
+}
+
+// bpmMatches tells if next can be brought within the tolerance of the BPM of
+// track, or of half or double of it and so on when the tempo options allow.
+func bpmMatches(track interfaces.Item, next interfaces.Item, tempo interfaces.TempoOptions) bool {
+	for _, multiplier := range tempo.Multipliers() {
+		pitched := next.AsBpm(track.GetBPM()/multiplier, tempo)
+		if track.BpmMatchesTarget(pitched.GetBPM()*multiplier, tempo) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasKey tells if a track has a key that can be matched, see
+// models.UnknownScale.
+func hasKey(track interfaces.Item) bool {
+	return track.GetScale().IsKnown()
+}
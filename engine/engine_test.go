@@ -0,0 +1,320 @@
+package engine
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/xdave/keyid/args"
+	"github.com/xdave/keyid/client"
+	"github.com/xdave/keyid/interfaces"
+	"github.com/xdave/keyid/models"
+)
+
+// memoryLibrary is a library of tracks in memory, whose now playing moves to
+// the next track on every call.
+type memoryLibrary struct {
+	tracks  []interfaces.Item
+	playing atomic.Int64
+}
+
+func newMemoryLibrary(n int) *memoryLibrary {
+	keys := []string{"8A", "8B", "9A", "7A", "9B", "7B"}
+	library := &memoryLibrary{}
+	for i := 0; i < n; i++ {
+		library.tracks = append(library.tracks, &client.Track{
+			ID:     fmt.Sprint(i),
+			BPM:    120 + float64(i%8),
+			Scale:  models.NewKey(keys[i%len(keys)]),
+			Artist: "artist",
+			Title:  fmt.Sprint("track ", i),
+			Tags:   []string{},
+		})
+	}
+	return library
+}
+
+func (l *memoryLibrary) LoadPlaylist(ctx context.Context, name string) (interfaces.Collection, error) {
+	return models.NewInMemoryCollection(l.tracks...), nil
+}
+
+func (l *memoryLibrary) GetPlaylists(ctx context.Context) ([]*interfaces.PlaylistNode, error) {
+	return []*interfaces.PlaylistNode{}, nil
+}
+
+func (l *memoryLibrary) GetNowPlaying(ctx context.Context) (interfaces.Item, error) {
+	return l.tracks[int(l.playing.Add(1))%len(l.tracks)], nil
+}
+
+func (l *memoryLibrary) Supports(capability interfaces.Capability) bool {
+	return capability == interfaces.CapabilityNowPlaying
+}
+
+func (l *memoryLibrary) Close() error {
+	return nil
+}
+
+// The GUI asks for now playing, suggests and generates from goroutines over
+// the same collection and history, and switches rule sets and tempo profiles
+// meanwhile. Run with -race.
+func TestEngineConcurrentOperations(t *testing.T) {
+	engine := New(&args.Args{
+		Rules:     "keyid",
+		Tempo:     "6%",
+		Tolerance: models.DefaultTempoTolerance,
+		StartWith: "track 0",
+	}, nil, newMemoryLibrary(200))
+	ctx := context.Background()
+	collection, err := engine.LoadPlaylist(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// GetNowPlaying only asks the library without -startWith
+	playing := New(&args.Args{Rules: "keyid", Tempo: "6%", Tolerance: models.DefaultTempoTolerance}, engine.history, engine.library)
+
+	const workers = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*4)
+	for i := 0; i < workers; i++ {
+		wg.Add(4)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				if _, err := playing.GetNowPlaying(ctx, collection); err != nil {
+					errs <- err
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				if _, err := playing.Suggest(ctx, collection); err != nil {
+					errs <- err
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 2; j++ {
+				playlist, err := engine.Generate(ctx, collection)
+				if err != nil {
+					errs <- err
+				} else if playlist.IsEmpty() {
+					errs <- fmt.Errorf("generated an empty playlist")
+				}
+			}
+		}()
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				rules := models.RuleSets[(i+j)%len(models.RuleSets)]
+				if err := playing.SetRules(rules.Name); err != nil {
+					errs <- err
+				}
+				profile := models.TempoProfiles[(i+j)%len(models.TempoProfiles)]
+				if err := playing.SetTempo(profile.Name, models.DefaultTempoTolerance); err != nil {
+					errs <- err
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+	if !engine.history.Contains(engine.library.(*memoryLibrary).tracks[1]) {
+		t.Error("now playing tracks are not in the history")
+	}
+}
+
+func TestGetCompatibleTracksTags(t *testing.T) {
+	tagged := func(id string, tags ...string) interfaces.Item {
+		return &client.Track{ID: id, BPM: 120, Scale: models.NewKey("8A"), Title: id, Tags: tags}
+	}
+	playing := tagged("playing")
+	collection := models.NewIndexedCollection(
+		playing,
+		tagged("techno", "techno"),
+		tagged("techno vocal", "techno", "vocal"),
+		tagged("house", "house"),
+		tagged("house vocal", "house", "vocal"),
+		tagged("untagged"),
+	)
+	tests := []struct {
+		name        string
+		tags        string
+		excludeTags string
+		want        []string
+	}{
+		{"no tags", "", "", []string{"techno", "techno vocal", "house", "house vocal", "untagged"}},
+		{"tags only", "techno, house", "", []string{"techno", "techno vocal", "house", "house vocal"}},
+		{"exclude only", "", "vocal", []string{"techno", "house", "untagged"}},
+		{"both", "techno", "vocal", []string{"techno"}},
+		{"no match", "ambient", "", []string{}},
+		{"empty entries", ",techno,,", ",", []string{"techno", "techno vocal"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := New(&args.Args{
+				Rules:       "keyid",
+				Tempo:       "6%",
+				Tolerance:   models.DefaultTempoTolerance,
+				Tags:        tt.tags,
+				ExcludeTags: tt.excludeTags,
+			}, nil, newMemoryLibrary(0))
+			got := []string{}
+			for _, item := range engine.GetCompatibleTracks(playing, collection).Items() {
+				got = append(got, item.GetID())
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("GetCompatibleTracks() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// Suggestions come best scored first, ties in collection order.
+func TestGetCompatibleTracksRanking(t *testing.T) {
+	keyTrack := func(id string, key string, bpm float64) interfaces.Item {
+		return &client.Track{ID: id, BPM: bpm, Scale: models.NewKey(key), Title: id, Tags: []string{}}
+	}
+	playing := keyTrack("playing", "8A", 120)
+	collection := models.NewIndexedCollection(
+		playing,
+		keyTrack("+1", "9A", 120),
+		keyTrack("same key, faster", "8A", 124),
+		keyTrack("no move", "2A", 120),
+		keyTrack("relative", "8B", 120),
+		keyTrack("same key", "8A", 120),
+		keyTrack("-1", "7A", 120),
+		keyTrack("too fast", "8A", 140),
+	)
+	engine := New(&args.Args{Rules: "keyid", Tempo: "6%", Tolerance: models.DefaultTempoTolerance, KeyLock: true}, nil, newMemoryLibrary(0))
+	got := []string{}
+	scores := []float64{}
+	for _, item := range engine.GetCompatibleTracks(playing, collection).Items() {
+		got = append(got, item.GetID())
+		scores = append(scores, item.(interfaces.Suggestion).GetTransition().Score)
+	}
+	want := []string{"same key", "relative", "+1", "-1", "same key, faster"}
+	if !slices.Equal(got, want) {
+		t.Errorf("GetCompatibleTracks() = %v, want %v", got, want)
+	}
+	if !slices.IsSortedFunc(scores, func(a, b float64) int { return cmp.Compare(b, a) }) {
+		t.Errorf("scores %v are not best first", scores)
+	}
+}
+
+// When nothing left mixes, Generate carries on with the best scored track,
+// here the ones -tags keeps out of the suggestions.
+func TestGenerateFallbackRanking(t *testing.T) {
+	track := func(id string, key string, bpm float64, tags ...string) interfaces.Item {
+		return &client.Track{ID: id, BPM: bpm, Scale: models.NewKey(key), Title: id, Tags: tags}
+	}
+	collection := models.NewIndexedCollection(
+		track("start", "8A", 120, "techno"),
+		track("+1", "9A", 120),
+		track("+1, faster", "9A", 124),
+		track("clash", "2A", 120),
+	)
+	engine := New(&args.Args{
+		Rules:     "keyid",
+		Tempo:     "6%",
+		Tolerance: models.DefaultTempoTolerance,
+		KeyLock:   true,
+		StartWith: "start",
+		Tags:      "techno",
+	}, nil, newMemoryLibrary(0))
+	playlist, err := engine.Generate(context.Background(), collection)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, item := range playlist.Items() {
+		got = append(got, item.GetID())
+	}
+	want := []string{"start", "+1", "+1, faster", "clash"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Generate() = %v, want %v", got, want)
+	}
+}
+
+func TestBpmMatches(t *testing.T) {
+	tests := []struct {
+		name  string
+		bpm   float64
+		next  float64
+		tempo interfaces.TempoOptions
+		want  bool
+	}{
+		{"within the tolerance", 120, 122, interfaces.TempoOptions{Tolerance: 1.8}, true},
+		{"past the tolerance", 120, 123, interfaces.TempoOptions{Tolerance: 1.8}, false},
+		{"within the pitch range", 120, 128, interfaces.TempoOptions{PitchRange: 6, Tolerance: 1.8}, true},
+		{"past the pitch range", 120, 132, interfaces.TempoOptions{PitchRange: 6, Tolerance: 1.8}, false},
+		{"half-time off", 170, 85, interfaces.TempoOptions{Tolerance: 1.8}, false},
+		{"half-time", 170, 86, interfaces.TempoOptions{Tolerance: 1.8, HalfTime: true}, true},
+		{"double-time", 70, 140, interfaces.TempoOptions{Tolerance: 1.8, HalfTime: true}, true},
+		{"3/4 feel", 120, 81, interfaces.TempoOptions{Tolerance: 1.8, ThreeFour: true}, true},
+		{"3/4 feel off", 120, 81, interfaces.TempoOptions{Tolerance: 1.8, HalfTime: true}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			track := &client.Track{BPM: tt.bpm, Scale: models.NewKey("8A")}
+			next := &client.Track{BPM: tt.next, Scale: models.NewKey("8A")}
+			if got := bpmMatches(track, next, tt.tempo); got != tt.want {
+				t.Errorf("bpmMatches(%v, %v) = %v, want %v", tt.bpm, tt.next, got, tt.want)
+			}
+		})
+	}
+}
+
+// candidates only narrows the collection down, every track that scores is
+// among them whatever the tempo options.
+func TestCandidatesKeepCompatibleTracks(t *testing.T) {
+	keys := []string{"8A", "8B", "9A", "7A", "3A", "1A", "2B", "D dorian", "G mixolydian", ""}
+	items := []interfaces.Item{}
+	for i := 0; i < 400; i++ {
+		items = append(items, &client.Track{
+			ID:    fmt.Sprint(i),
+			BPM:   60 + float64(i%150)*0.9,
+			Scale: models.NewKey(keys[i%len(keys)]),
+			Tags:  []string{},
+		})
+	}
+	collection := models.NewIndexedCollection(items...)
+	scored := 0
+	for _, profile := range models.TempoProfiles {
+		for _, tempo := range []interfaces.TempoOptions{
+			{PitchRange: profile.PitchRange, Tolerance: models.DefaultTempoTolerance},
+			{PitchRange: profile.PitchRange, Tolerance: models.DefaultTempoTolerance, KeyLock: true},
+			{PitchRange: profile.PitchRange, Tolerance: 4, HalfTime: true, ThreeFour: true},
+		} {
+			for _, unknownKeys := range []bool{false, true} {
+				for _, track := range []interfaces.Item{items[0], items[7], items[9], items[123]} {
+					found := map[string]bool{}
+					for _, item := range candidates(track, collection, models.KeyidRules, tempo, unknownKeys) {
+						found[item.GetID()] = true
+					}
+					engine := &Engine{args: &args.Args{UnknownKeys: unknownKeys}}
+					for _, item := range items {
+						if !engine.score(models.KeyidRules, track, item, tempo).IsCompatible() {
+							continue
+						}
+						scored++
+						if !found[item.GetID()] {
+							t.Errorf("%+v unknownKeys %v: %v from %v scores but is no candidate", tempo, unknownKeys, item, track)
+						}
+					}
+				}
+			}
+		}
+	}
+	if scored == 0 {
+		t.Error("no track scored")
+	}
+}
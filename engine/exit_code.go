@@ -0,0 +1,38 @@
+package engine
+
+import (
+	"errors"
+
+	"github.com/xdave/keyid/interfaces"
+)
+
+// Exit codes of the CLI, so scripts can tell why keyid failed.
+const (
+	ExitFailure          = 1
+	ExitPlaylistNotFound = 2
+	ExitTrackNotFound    = 3
+	ExitDatabaseLocked   = 4
+	ExitKeyMissing       = 5
+)
+
+// ExitCode maps an error to the exit code of the CLI.
+func ExitCode(err error) int {
+	var playlistNotFound *interfaces.PlaylistNotFoundError
+	var trackNotFound *interfaces.TrackNotFoundError
+	var databaseLocked *interfaces.DatabaseLockedError
+	var keyMissing *interfaces.KeyMissingError
+
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &playlistNotFound):
+		return ExitPlaylistNotFound
+	case errors.As(err, &trackNotFound):
+		return ExitTrackNotFound
+	case errors.As(err, &databaseLocked):
+		return ExitDatabaseLocked
+	case errors.As(err, &keyMissing):
+		return ExitKeyMissing
+	}
+	return ExitFailure
+}
@@ -0,0 +1,39 @@
+package engine
+
+import (
+	"github.com/xdave/keyid/interfaces"
+	"github.com/xdave/keyid/models"
+
+	"go.uber.org/fx"
+)
+
+// History remembers the tracks that have been played, so they are not
+// suggested again. It is safe to use from several goroutines.
+type History struct {
+	tracks interfaces.Collection
+}
+
+type HistoryParams struct {
+	fx.In
+}
+
+type HistoryResult struct {
+	fx.Out
+	History *History
+}
+
+func NewHistory(params HistoryParams) HistoryResult {
+	return HistoryResult{
+		History: &History{
+			tracks: models.NewSyncCollection(),
+		},
+	}
+}
+
+func (h *History) Add(track interfaces.Item) {
+	h.tracks.Add(track)
+}
+
+func (h *History) Contains(track interfaces.Item) bool {
+	return h.tracks.Contains(track)
+}
@@ -0,0 +1,10 @@
+package engine
+
+import "go.uber.org/fx"
+
+var Module = fx.Module("engine",
+	fx.Provide(
+		NewHistory,
+		NewEngine,
+	),
+)
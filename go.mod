@@ -8,6 +8,7 @@ require (
 	fyne.io/fyne/v2 v2.6.3
 	github.com/dvcrn/go-rekordbox v0.0.0-20231108014618-009cde44fc50
 	github.com/mattn/go-nulltype v0.0.0-20230117041332-6715e831ac05
+	github.com/xeodou/go-sqlcipher v0.0.0-20200727080346-d681773ef093
 	go.uber.org/fx v1.21.0
 )
 
@@ -40,7 +41,6 @@ require (
 	github.com/srwiley/oksvg v0.0.0-20221011165216-be6e8873101c // indirect
 	github.com/srwiley/rasterx v0.0.0-20220730225603-2ab79fcdd4ef // indirect
 	github.com/stretchr/testify v1.10.0 // indirect
-	github.com/xeodou/go-sqlcipher v0.0.0-20200727080346-d681773ef093 // indirect
 	github.com/yuin/goldmark v1.7.8 // indirect
 	go.uber.org/dig v1.17.1 // indirect
 	go.uber.org/multierr v1.11.0 // indirect
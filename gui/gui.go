@@ -1,8 +1,10 @@
 package gui
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"sync"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
@@ -10,6 +12,7 @@ import (
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
 	"github.com/xdave/keyid/interfaces"
+	"github.com/xdave/keyid/models"
 	"go.uber.org/fx"
 )
 
@@ -17,6 +20,8 @@ import (
 type GUI struct {
 	client interfaces.Client
 	w      fyne.Window
+	// How keys are written in the tables, from -keyNotation
+	keyNotation *models.KeyNotation
 
 	// UI Components
 	playlistTree        *widget.Tree
@@ -33,6 +38,12 @@ type GUI struct {
 	exportBtn     *widget.Button
 	refreshBtn    *widget.Button
 	nowPlayingBtn *widget.Button
+	cancelBtn     *widget.Button
+
+	// Settings
+	rulesSelect    *widget.Select
+	tempoSelect    *widget.Select
+	toleranceEntry *widget.Entry
 
 	// Data
 	playlists        []*interfaces.PlaylistNode
@@ -41,10 +52,15 @@ type GUI struct {
 	suggestedTracks  []interfaces.Item
 	generatedTracks  []interfaces.Item
 	selectedPlaylist *interfaces.PlaylistNode
+
+	// The running load or generate, nil when idle
+	operation       context.Context
+	cancelOperation context.CancelFunc
+	operationMu     sync.Mutex
 }
 
 // Show initializes and runs the GUI application.
-func Show(client interfaces.Client, shutdowner fx.Shutdowner) {
+func Show(client interfaces.Client, notation *models.KeyNotation, shutdowner fx.Shutdowner) {
 	a := app.NewWithID("com.github.xdave.keyid")
 	a.Settings().SetTheme(theme.DarkTheme())
 
@@ -57,6 +73,7 @@ func Show(client interfaces.Client, shutdowner fx.Shutdowner) {
 	gui := &GUI{
 		client:      client,
 		w:           w,
+		keyNotation: notation,
 		playlistMap: make(map[string]*interfaces.PlaylistNode),
 	}
 
@@ -78,7 +95,10 @@ func Show(client interfaces.Client, shutdowner fx.Shutdowner) {
 func (g *GUI) initialize() error {
 	g.updateStatus("Loading playlists...")
 
-	clientPlaylists := g.client.GetPlaylists()
+	clientPlaylists, err := g.client.GetPlaylists(context.Background())
+	if err != nil {
+		return err
+	}
 	playlists := make([]*interfaces.PlaylistNode, 0)
 	for _, p := range clientPlaylists {
 		if p != nil {
@@ -129,8 +149,18 @@ func (g *GUI) updateButtonStates() {
 		g.nowPlayingBtn.Disable()
 	}
 
+	// Sources without history can only suggest from -startWith
+	if !g.client.Supports(interfaces.CapabilityNowPlaying) {
+		g.nowPlayingBtn.Disable()
+	}
+
 	g.exportBtn.Enable()
 	if len(g.generatedTracks) == 0 {
 		g.exportBtn.Disable()
 	}
+
+	g.cancelBtn.Disable()
+	if g.isBusy() {
+		g.cancelBtn.Enable()
+	}
 }
@@ -1,8 +1,11 @@
 package gui
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"strconv"
+	"strings"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/dialog"
@@ -38,20 +41,33 @@ func (g *GUI) loadPlaylist(node *interfaces.PlaylistNode) {
 	g.generatedTracks = []interfaces.Item{}
 	g.suggestionsTable.Refresh()
 	g.generatedTable.Refresh()
+	g.currentTracks = nil
 
-	tracks := g.client.LoadPlaylist(node.Name)
-	if tracks == nil {
-		g.showError(fmt.Sprintf("Failed to load playlist: %s", node.Name))
-		g.playlistInfoLabel.ParseMarkdown("**Failed to load playlist**")
-		g.currentTracks = nil
-	} else {
-		g.currentTracks = tracks
-		trackCount := tracks.Len()
-		g.playlistInfoLabel.ParseMarkdown(fmt.Sprintf("**Playlist:** %s  \n**Tracks:** %d", node.Name, trackCount))
-		g.updateStatus(fmt.Sprintf("Loaded %d tracks from %s", trackCount, node.Name))
-		log.Printf("Successfully loaded playlist '%s' with %d tracks", node.Name, trackCount)
-	}
+	// Loading a whole collection can take a while, keep the UI responsive
+	ctx := g.startOperation()
 	g.updateButtonStates()
+	go func() {
+		tracks, err := g.client.LoadPlaylist(ctx, node.Name)
+		if !g.finishOperation(ctx) {
+			return
+		}
+
+		fyne.Do(func() {
+			if err != nil {
+				g.showOperationError(fmt.Sprintf("Failed to load playlist %s", node.Name), err)
+				g.playlistInfoLabel.ParseMarkdown("**Failed to load playlist**")
+				g.currentTracks = nil
+			} else {
+				g.currentTracks = tracks
+				trackCount := tracks.Len()
+				unknownKeys := g.client.GetUnknownKeys(tracks).Len()
+				g.playlistInfoLabel.ParseMarkdown(fmt.Sprintf("**Playlist:** %s  \n**Tracks:** %d  \n**Unknown keys:** %d", node.Name, trackCount, unknownKeys))
+				g.updateStatus(fmt.Sprintf("Loaded %d tracks from %s", trackCount, node.Name))
+				log.Printf("Successfully loaded playlist '%s' with %d tracks", node.Name, trackCount)
+			}
+			g.updateButtonStates()
+		})
+	}()
 }
 
 // handleRefresh reloads all playlist data from the client.
@@ -72,23 +88,31 @@ func (g *GUI) handleShowNowPlaying() {
 	g.updateStatus("Getting current track...")
 	g.nowPlayingInfoLabel.ParseMarkdown("_Loading now playing..._")
 
-	// Run the potentially long-running operation in a separate goroutine.
+	// Run the potentially long-running operation in a separate goroutine,
+	// with the tracks captured here since loading a playlist replaces them.
+	tracks := g.currentTracks
 	go func() {
-		currentTrack := g.client.GetNowPlaying(g.currentTracks)
-
-		// Once the data is retrieved, update the UI elements.
-		// Fyne's widget operations are thread-safe.
-		if currentTrack == nil {
-			g.nowPlayingInfoLabel.ParseMarkdown("**No track is currently playing**")
-			g.updateStatus("No track playing")
-			return
-		}
-
-		trackInfo := fmt.Sprintf("**Title:** %s  \n**Artist:** %s  \n**BPM:** %.1f  \n**Key:** %s",
-			currentTrack.GetTitle(), currentTrack.GetArtist(), currentTrack.GetBPM(), currentTrack.GetScale().String())
-
-		g.nowPlayingInfoLabel.ParseMarkdown(trackInfo)
-		g.updateStatus(fmt.Sprintf("Now Playing: %s", currentTrack.GetTitle()))
+		currentTrack, err := g.client.GetNowPlaying(context.Background(), tracks)
+
+		// Once the data is retrieved, update the UI elements on the UI thread.
+		fyne.Do(func() {
+			if err != nil {
+				g.nowPlayingInfoLabel.ParseMarkdown("**Failed to get the current track**")
+				g.showError(fmt.Sprintf("Failed to get the current track: %v", err))
+				return
+			}
+			if currentTrack == nil {
+				g.nowPlayingInfoLabel.ParseMarkdown("**No track is currently playing**")
+				g.updateStatus("No track playing")
+				return
+			}
+
+			trackInfo := fmt.Sprintf("**Title:** %s  \n**Artist:** %s  \n**BPM:** %.1f  \n**Key:** %s",
+				currentTrack.GetTitle(), currentTrack.GetArtist(), currentTrack.GetBPM(), g.keyNotation.Format(currentTrack.GetScale()))
+
+			g.nowPlayingInfoLabel.ParseMarkdown(trackInfo)
+			g.updateStatus(fmt.Sprintf("Now Playing: %s", currentTrack.GetTitle()))
+		})
 	}()
 }
 
@@ -99,10 +123,17 @@ func (g *GUI) handleSuggest() {
 		return
 	}
 	g.updateStatus("Getting track suggestions...")
-	suggestedCollection := g.client.Suggest(g.currentTracks)
-	if suggestedCollection == nil {
+	suggestedCollection, err := g.client.Suggest(context.Background(), g.currentTracks)
+	if err != nil {
+		g.suggestedTracks = []interfaces.Item{}
+		g.showError(fmt.Sprintf("Failed to get suggestions: %v", err))
+	} else if suggestedCollection.IsEmpty() {
 		g.suggestedTracks = []interfaces.Item{}
-		g.updateStatus("No suggestions found")
+		if g.client.Supports(interfaces.CapabilityNowPlaying) {
+			g.updateStatus("No suggestions found")
+		} else {
+			g.updateStatus("This source can't tell what is playing, start keyid with -startWith to pick a track")
+		}
 	} else {
 		g.suggestedTracks = suggestedCollection.Items()
 		g.updateStatus(fmt.Sprintf("Found %d suggested tracks", len(g.suggestedTracks)))
@@ -117,17 +148,69 @@ func (g *GUI) handleGenerate() {
 		return
 	}
 	g.updateStatus("Generating playlist...")
-	generatedCollection := g.client.Generate(g.currentTracks)
-	if generatedCollection == nil {
-		g.generatedTracks = []interfaces.Item{}
-		g.updateStatus("Failed to generate playlist")
-		g.showError("Failed to generate playlist")
-	} else {
-		g.generatedTracks = generatedCollection.Items()
-		g.updateStatus(fmt.Sprintf("Generated playlist with %d tracks", len(g.generatedTracks)))
+	g.generateBtn.Disable()
+
+	ctx := g.startOperation()
+	tracks := g.currentTracks
+	go func() {
+		generatedCollection, err := g.client.Generate(ctx, tracks)
+		if !g.finishOperation(ctx) {
+			return
+		}
+
+		fyne.Do(func() {
+			if err != nil {
+				g.generatedTracks = []interfaces.Item{}
+				g.showOperationError("Failed to generate playlist", err)
+			} else {
+				g.generatedTracks = generatedCollection.Items()
+				g.updateStatus(fmt.Sprintf("Generated playlist with %d tracks", len(g.generatedTracks)))
+			}
+			g.generatedTable.Refresh()
+			g.updateButtonStates()
+		})
+	}()
+}
+
+// handleRulesChanged switches the harmonic rule set of the next suggest or
+// generate.
+func (g *GUI) handleRulesChanged(name string) {
+	if err := g.client.SetRules(name); err != nil {
+		g.showError(fmt.Sprintf("Failed to select rule set: %v", err))
+		return
 	}
-	g.generatedTable.Refresh()
-	g.updateButtonStates()
+	g.updateStatus(fmt.Sprintf("Using the '%s' rule set", name))
+}
+
+// handleTempoChanged switches the pitch range of the next suggest or generate.
+func (g *GUI) handleTempoChanged(profile string) {
+	if err := g.client.SetTempo(profile, g.client.GetTempo().Tolerance); err != nil {
+		g.showError(fmt.Sprintf("Failed to select pitch range: %v", err))
+		return
+	}
+	g.updateStatus(fmt.Sprintf("Using a %s pitch range", profile))
+}
+
+// handleToleranceSubmitted sets the BPM tolerance typed in, putting back the
+// current one when it isn't valid.
+func (g *GUI) handleToleranceSubmitted(text string) {
+	tempo := g.client.GetTempo()
+	tolerance, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(text, "%")), 64)
+	if err == nil {
+		err = g.client.SetTempo(tempo.Profile, tolerance)
+	}
+	if err != nil {
+		g.toleranceEntry.SetText(strconv.FormatFloat(tempo.Tolerance, 'f', -1, 64))
+		g.showError(fmt.Sprintf("Failed to set BPM tolerance: %v", err))
+		return
+	}
+	g.updateStatus(fmt.Sprintf("BPMs within %g%% mix", tolerance))
+}
+
+// handleCancel stops a running playlist load or generate.
+func (g *GUI) handleCancel() {
+	g.updateStatus("Cancelling...")
+	g.cancelRunningOperation()
 }
 
 // handleExport saves the generated playlist to an M3U file.
@@ -1,6 +1,8 @@
 package gui
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 
@@ -39,6 +41,63 @@ func (g *GUI) writeM3UFile(writer fyne.URIWriteCloser) error {
 	return nil
 }
 
+// startOperation returns the context of a cancellable operation, cancelling
+// the one still running, and enables the Cancel button.
+func (g *GUI) startOperation() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	g.operationMu.Lock()
+	if g.cancelOperation != nil {
+		g.cancelOperation()
+	}
+	g.operation = ctx
+	g.cancelOperation = cancel
+	g.operationMu.Unlock()
+
+	g.cancelBtn.Enable()
+	return ctx
+}
+
+// finishOperation releases the context of the operation started with ctx. It
+// returns false when a newer operation replaced it, whose result wins.
+func (g *GUI) finishOperation(ctx context.Context) bool {
+	g.operationMu.Lock()
+	defer g.operationMu.Unlock()
+
+	if g.operation != ctx {
+		return false
+	}
+	g.cancelOperation()
+	g.operation = nil
+	g.cancelOperation = nil
+	return true
+}
+
+// cancelRunningOperation cancels the running operation, if any.
+func (g *GUI) cancelRunningOperation() {
+	g.operationMu.Lock()
+	defer g.operationMu.Unlock()
+	if g.cancelOperation != nil {
+		g.cancelOperation()
+	}
+}
+
+// isBusy tells if a cancellable operation is running.
+func (g *GUI) isBusy() bool {
+	g.operationMu.Lock()
+	defer g.operationMu.Unlock()
+	return g.cancelOperation != nil
+}
+
+// showOperationError shows why an operation failed, or that it was cancelled.
+func (g *GUI) showOperationError(message string, err error) {
+	if errors.Is(err, context.Canceled) {
+		g.updateStatus("Cancelled")
+		return
+	}
+	g.showError(fmt.Sprintf("%s: %v", message, err))
+}
+
 // updateStatus updates the text in the status bar and logs the message.
 func (g *GUI) updateStatus(message string) {
 	if g.statusBar != nil {
@@ -51,5 +110,5 @@ func (g *GUI) updateStatus(message string) {
 func (g *GUI) showError(message string) {
 	log.Printf("Error: %s", message)
 	g.updateStatus(fmt.Sprintf("Error: %s", message))
-	dialog.ShowError(fmt.Errorf(message), g.w)
+	dialog.ShowError(errors.New(message), g.w)
 }
@@ -1,10 +1,14 @@
 package gui
 
 import (
+	"slices"
+	"strconv"
+
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
+	"github.com/xdave/keyid/models"
 )
 
 // createWidgets initializes all the UI widgets for the GUI.
@@ -43,15 +47,33 @@ func (g *GUI) createActionButtons() {
 	g.suggestBtn = widget.NewButtonWithIcon("Get Suggestions", theme.SearchIcon(), g.handleSuggest)
 	g.generateBtn = widget.NewButtonWithIcon("Generate Playlist", theme.MediaPlayIcon(), g.handleGenerate)
 	g.exportBtn = widget.NewButtonWithIcon("Export M3U", theme.DocumentSaveIcon(), g.handleExport)
+	g.cancelBtn = widget.NewButtonWithIcon("Cancel", theme.CancelIcon(), g.handleCancel)
 
 	g.suggestBtn.Importance = widget.HighImportance
 	g.generateBtn.Importance = widget.HighImportance
+
+	// Custom rule sets from -rules show up next to the presets
+	ruleSets := models.RuleSetNames()
+	if current := g.client.GetRules(); !slices.Contains(ruleSets, current) {
+		ruleSets = append(ruleSets, current)
+	}
+	g.rulesSelect = widget.NewSelect(ruleSets, nil)
+	g.rulesSelect.SetSelected(g.client.GetRules())
+	g.rulesSelect.OnChanged = g.handleRulesChanged
+
+	tempo := g.client.GetTempo()
+	g.tempoSelect = widget.NewSelect(models.TempoProfileNames(), nil)
+	g.tempoSelect.SetSelected(tempo.Profile)
+	g.tempoSelect.OnChanged = g.handleTempoChanged
+	g.toleranceEntry = widget.NewEntry()
+	g.toleranceEntry.SetText(strconv.FormatFloat(tempo.Tolerance, 'f', -1, 64))
+	g.toleranceEntry.OnSubmitted = g.handleToleranceSubmitted
 }
 
 // createSuggestionsTable creates the table for suggested tracks.
 func (g *GUI) createSuggestionsTable() {
 	g.suggestionsTable = widget.NewTable(
-		func() (int, int) { return len(g.suggestedTracks) + 1, 4 },
+		func() (int, int) { return len(g.suggestedTracks) + 1, 5 },
 		func() fyne.CanvasObject { return widget.NewLabel("template") },
 		func(id widget.TableCellID, cell fyne.CanvasObject) {
 			g.updateTrackCell(id, cell, g.suggestedTracks)
@@ -61,12 +83,13 @@ func (g *GUI) createSuggestionsTable() {
 	g.suggestionsTable.SetColumnWidth(1, 200) // Artist
 	g.suggestionsTable.SetColumnWidth(2, 80)  // BPM
 	g.suggestionsTable.SetColumnWidth(3, 80)  // Key
+	g.suggestionsTable.SetColumnWidth(4, 320) // Why
 }
 
 // createGeneratedTable creates the table for the generated playlist.
 func (g *GUI) createGeneratedTable() {
 	g.generatedTable = widget.NewTable(
-		func() (int, int) { return len(g.generatedTracks) + 1, 4 },
+		func() (int, int) { return len(g.generatedTracks) + 1, 5 },
 		func() fyne.CanvasObject { return widget.NewLabel("template") },
 		func(id widget.TableCellID, cell fyne.CanvasObject) {
 			g.updateTrackCell(id, cell, g.generatedTracks)
@@ -76,6 +99,7 @@ func (g *GUI) createGeneratedTable() {
 	g.generatedTable.SetColumnWidth(1, 200) // Artist
 	g.generatedTable.SetColumnWidth(2, 80)  // BPM
 	g.generatedTable.SetColumnWidth(3, 80)  // Key
+	g.generatedTable.SetColumnWidth(4, 320) // Why
 }
 
 // setupLayout assembles the created widgets into the final window layout.
@@ -87,7 +111,11 @@ func (g *GUI) setupLayout() {
 	leftPanel := container.NewBorder(g.infoCard, leftPanelBottomButtons, nil, nil, playlistCard)
 
 	// Right Panel
-	buttonBar := container.NewHBox(g.suggestBtn, g.generateBtn, g.exportBtn)
+	toleranceField := container.NewGridWrap(fyne.NewSize(60, g.toleranceEntry.MinSize().Height), g.toleranceEntry)
+	buttonBar := container.NewHBox(g.suggestBtn, g.generateBtn, g.cancelBtn, g.exportBtn,
+		widget.NewLabel("Rules:"), g.rulesSelect,
+		widget.NewLabel("Pitch range:"), g.tempoSelect,
+		widget.NewLabel("Tolerance %:"), toleranceField)
 	tabs := container.NewAppTabs(
 		container.NewTabItem("Suggestions", g.suggestionsTable),
 		container.NewTabItem("Generated Playlist", g.generatedTable),
@@ -2,11 +2,12 @@ package gui
 
 import (
 	"github.com/xdave/keyid/interfaces"
+	"github.com/xdave/keyid/models"
 	"go.uber.org/fx"
 )
 
 var Module = fx.Options(
-	fx.Invoke(func(client interfaces.Client, shutdowner fx.Shutdowner) {
-		Show(client, shutdowner)
+	fx.Invoke(func(client interfaces.Client, notation *models.KeyNotation, shutdowner fx.Shutdowner) {
+		Show(client, notation, shutdowner)
 	}),
 )
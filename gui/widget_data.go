@@ -77,6 +77,8 @@ func (g *GUI) updateTrackCell(id widget.TableCellID, cell fyne.CanvasObject, tra
 			label.SetText("BPM")
 		case 3:
 			label.SetText("Key")
+		case 4:
+			label.SetText("Why")
 		}
 		return
 	}
@@ -91,6 +93,12 @@ func (g *GUI) updateTrackCell(id widget.TableCellID, cell fyne.CanvasObject, tra
 	case 2:
 		label.SetText(fmt.Sprintf("%.1f", track.GetBPM()))
 	case 3:
-		label.SetText(track.GetScale().String())
+		label.SetText(g.keyNotation.Format(track.GetScale()))
+	case 4:
+		// Empty for the track a generated playlist starts with
+		label.SetText("")
+		if suggestion, ok := track.(interfaces.Suggestion); ok {
+			label.SetText(suggestion.GetTransition().String())
+		}
 	}
 }
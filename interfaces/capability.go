@@ -0,0 +1,13 @@
+package interfaces
+
+// Capability is an optional feature a track source may support.
+type Capability string
+
+const (
+	// CapabilityNowPlaying means the source can report the track that is playing
+	CapabilityNowPlaying Capability = "now-playing"
+	// CapabilityPlaylistTree means the source has playlists or folders to browse
+	CapabilityPlaylistTree Capability = "playlist-tree"
+	// CapabilityTags means tracks carry tags usable with -tags and -excludeTags
+	CapabilityTags Capability = "tags"
+)
@@ -1,5 +1,7 @@
 package interfaces
 
+import "context"
+
 type PlaylistNode struct {
 	ID       string
 	Name     string
@@ -7,13 +9,20 @@ type PlaylistNode struct {
 }
 
 type Client interface {
-	LoadPlaylist(name string) Collection
-	GetPlaylists() []*PlaylistNode
-	GetTrackByTitle(pattern string, from Collection) Item
-	GetNowPlaying(collection Collection) Item
+	LoadPlaylist(ctx context.Context, name string) (Collection, error)
+	GetPlaylists(ctx context.Context) ([]*PlaylistNode, error)
+	GetTrackByTitle(pattern string, from Collection) (Item, error)
+	GetNowPlaying(ctx context.Context, collection Collection) (Item, error)
 	GetCompatibleTracks(track Item, from Collection) Collection
-	Suggest(collection Collection) Collection
-	Generate(collection Collection) Collection
-	Run()
-	Close()
+	ScoreTransition(track Item, next Item) Transition
+	Suggest(ctx context.Context, collection Collection) (Collection, error)
+	Generate(ctx context.Context, collection Collection) (Collection, error)
+	GetUnknownKeys(collection Collection) Collection
+	SetRules(name string) error
+	GetRules() string
+	SetTempo(profile string, tolerance float64) error
+	GetTempo() TempoOptions
+	Supports(capability Capability) bool
+	Run() error
+	Close() error
 }
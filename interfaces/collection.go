@@ -21,3 +21,20 @@ type Collection interface {
 	Items() []Item
 	RandomShuffle()
 }
+
+// CandidateQuery selects the items with a BPM between MinBPM and MaxBPM whose
+// key passes KeyFilter. KeyFilter is called once per distinct key.
+type CandidateQuery struct {
+	MinBPM    float64
+	MaxBPM    float64
+	KeyFilter func(scale Scale) bool
+}
+
+// IndexedCollection is a Collection that finds items by key and BPM without
+// scanning all of them.
+type IndexedCollection interface {
+	Collection
+	// Candidates returns the items any of the queries select, once each and
+	// in collection order.
+	Candidates(queries ...CandidateQuery) []Item
+}
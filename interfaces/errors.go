@@ -0,0 +1,46 @@
+package interfaces
+
+import "fmt"
+
+// PlaylistNotFoundError is returned when a source has no playlist by that name.
+type PlaylistNotFoundError struct {
+	Name string
+}
+
+func (e *PlaylistNotFoundError) Error() string {
+	return fmt.Sprintf("cannot find a playlist with name '%s'", e.Name)
+}
+
+// TrackNotFoundError is returned when no track matches a title or a path.
+type TrackNotFoundError struct {
+	Pattern string
+}
+
+func (e *TrackNotFoundError) Error() string {
+	return fmt.Sprintf("cannot find a track matching '%s'", e.Pattern)
+}
+
+// DatabaseLockedError is returned when the DJ software holds a lock on its
+// database, usually because it is running and writing to it.
+type DatabaseLockedError struct {
+	Path string
+	Err  error
+}
+
+func (e *DatabaseLockedError) Error() string {
+	return fmt.Sprintf("database '%s' is locked, close the DJ software and try again: %v", e.Path, e.Err)
+}
+
+func (e *DatabaseLockedError) Unwrap() error {
+	return e.Err
+}
+
+// KeyMissingError is returned when a track needs a key to be matched but has
+// none, e.g. because it was never analyzed.
+type KeyMissingError struct {
+	Track Item
+}
+
+func (e *KeyMissingError) Error() string {
+	return fmt.Sprintf("'%s - %s' has no key, analyze it first", e.Track.GetArtist(), e.Track.GetTitle())
+}
@@ -9,9 +9,8 @@ type Item interface {
 	GetEnergy() int
 	Equals(other Item) bool
 	String() string
-	BpmMatchesTarget(targetBpm float64) bool
-	IsCompatible(other Item) bool
-	AsBpm(targetBpm float64) Item
+	BpmMatchesTarget(targetBpm float64, tempo TempoOptions) bool
+	AsBpm(targetBpm float64, tempo TempoOptions) Item
 	GetPath() string
 	GetDateAdded() string
 	GetTags() []string
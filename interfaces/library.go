@@ -0,0 +1,14 @@
+package interfaces
+
+import "context"
+
+// Library is a track source: a DJ software database, an export or a folder.
+// It only reads tracks, the mixing rules live in the engine.
+type Library interface {
+	LoadPlaylist(ctx context.Context, name string) (Collection, error)
+	GetPlaylists(ctx context.Context) ([]*PlaylistNode, error)
+	// GetNowPlaying returns the last played track, or nil when unknown
+	GetNowPlaying(ctx context.Context) (Item, error)
+	Supports(capability Capability) bool
+	Close() error
+}
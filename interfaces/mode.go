@@ -5,4 +5,5 @@ type Mode = string
 const (
 	ModeGenerate Mode = "generate"
 	ModeSuggest  Mode = "suggest"
+	ModeKeys     Mode = "keys"
 )
@@ -12,7 +12,9 @@ type Scale interface {
 	FlatToMinor() Scale
 	MajorToMinor() Scale
 	IsEqual(other Scale) bool
-	IsCompatible(other Scale) bool
+	// IsKnown is false for tracks without a key, or with one that can't be
+	// read, which never match
+	IsKnown() bool
 }
 
 func ModCyclic(num, modulus int) int {
@@ -7,15 +7,82 @@ type ScaleKind rune
 const (
 	Minor ScaleKind = 'A'
 	Major ScaleKind = 'B'
+	// The other church modes. Each plays the notes of a major key from another
+	// degree, D dorian the notes of C major, and takes the Camelot number of
+	// that key.
+	Dorian     ScaleKind = 'D'
+	Phrygian   ScaleKind = 'P'
+	Lydian     ScaleKind = 'L'
+	Mixolydian ScaleKind = 'M'
+	Locrian    ScaleKind = 'O'
+	// Ionian and Aeolian are the major and minor keys
+	Ionian  = Major
+	Aeolian = Minor
 )
 
+// ScaleKinds are every kind, in the order of their degree in the major scale.
+var ScaleKinds = []ScaleKind{Major, Dorian, Phrygian, Lydian, Mixolydian, Minor, Locrian}
+
+// Swap turns major into minor and back. Modes have no counterpart on the
+// Camelot wheel and stay the same.
 func (kind ScaleKind) Swap() ScaleKind {
-	if kind == Minor {
+	switch kind {
+	case Minor:
 		return Major
+	case Major:
+		return Minor
+	}
+	return kind
+}
+
+// IsMode is true for the kinds other than major and minor.
+func (kind ScaleKind) IsMode() bool {
+	return kind != Major && kind != Minor
+}
+
+// Degree is how many semitones the tonic is above the tonic of the major key
+// with the same notes.
+func (kind ScaleKind) Degree() int {
+	switch kind {
+	case Dorian:
+		return 2
+	case Phrygian:
+		return 4
+	case Lydian:
+		return 5
+	case Mixolydian:
+		return 7
+	case Minor:
+		return 9
+	case Locrian:
+		return 11
 	}
-	return Minor
+	return 0
 }
 
+// Name is how musical notation spells the kind out.
+func (kind ScaleKind) Name() string {
+	switch kind {
+	case Minor:
+		return "minor"
+	case Dorian:
+		return "dorian"
+	case Phrygian:
+		return "phrygian"
+	case Lydian:
+		return "lydian"
+	case Mixolydian:
+		return "mixolydian"
+	case Locrian:
+		return "locrian"
+	}
+	return "major"
+}
+
+// String is the Camelot letter, or the name of a mode, which has none.
 func (kind ScaleKind) String() string {
+	if kind.IsMode() {
+		return kind.Name()
+	}
 	return fmt.Sprintf("%c", kind)
 }
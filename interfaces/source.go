@@ -0,0 +1,13 @@
+package interfaces
+
+type Source = string
+
+const (
+	SourceRekordbox Source = "rekordbox"
+	SourceXML       Source = "xml"
+	SourceTraktor   Source = "traktor"
+	SourceSerato    Source = "serato"
+	SourceEngine    Source = "engine"
+	SourceMixxx     Source = "mixxx"
+	SourceFolder    Source = "folder"
+)
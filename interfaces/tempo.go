@@ -0,0 +1,56 @@
+package interfaces
+
+import "math"
+
+// TempoOptions says how a track is brought to the tempo of the one playing.
+type TempoOptions struct {
+	// Profile names the pitch range, see models.TempoProfiles
+	Profile string
+	// PitchRange is how far the pitch fader goes either way, in percent of
+	// a track's BPM, 0 when tracks play at their own tempo
+	PitchRange float64
+	// Tolerance is how far apart two BPMs can be and still mix, in percent
+	Tolerance float64
+	// KeyLock keeps the key of a track whatever its tempo, like the key lock
+	// (master tempo) of a deck
+	KeyLock bool
+	// HalfTime also mixes tracks at half or double the tempo, like 170 BPM
+	// drum & bass with 85, and ThreeFour at 1.5 times or two thirds of it
+	HalfTime  bool
+	ThreeFour bool
+}
+
+// Multipliers returns what the BPM of a track can be multiplied by to match
+// another, 1 first.
+func (t TempoOptions) Multipliers() []float64 {
+	multipliers := []float64{1}
+	if t.HalfTime {
+		multipliers = append(multipliers, 2, 0.5)
+	}
+	if t.ThreeFour {
+		multipliers = append(multipliers, 1.5, 2.0/3)
+	}
+	return multipliers
+}
+
+// TempoFamily names a multiplier other than 1: "half-time" when a track's
+// BPM is doubled to match, "double-time" when it's halved and "3/4 feel" for
+// the others.
+func TempoFamily(multiplier float64) string {
+	switch multiplier {
+	case 0, 1:
+		return ""
+	case 2:
+		return "half-time"
+	case 0.5:
+		return "double-time"
+	default:
+		return "3/4 feel"
+	}
+}
+
+// PitchShift returns how many semitones the key of a track at bpm moves when
+// it plays at targetBpm without key lock, 12·log2 of the tempo ratio.
+func PitchShift(bpm, targetBpm float64) float64 {
+	return 12 * math.Log2(targetBpm/bpm)
+}
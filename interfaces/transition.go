@@ -0,0 +1,67 @@
+package interfaces
+
+import (
+	"fmt"
+	"math"
+)
+
+// Transition rates mixing from the track playing into the next one. The zero
+// Transition means the tracks don't mix.
+type Transition struct {
+	// Score is between 0 and 1, higher mixes better
+	Score float64
+	// Move names the key move of the rule set that matched
+	Move string
+	// BPMDelta is the tempo change in percent of the track playing
+	BPMDelta float64
+	// Pitch is how many semitones playing the next track at the same tempo
+	// moved its key, Detune how far that is from the key it was rounded to.
+	// Both are 0 with key lock.
+	Pitch  int
+	Detune float64
+	// Multiplier is what the next track's BPM is multiplied by to match the
+	// track playing, 2 when it mixes half-time, see TempoFamily
+	Multiplier float64
+}
+
+func (t Transition) IsCompatible() bool {
+	return t.Score > 0
+}
+
+// Name is the move along with the tempo family and the pitch shift, e.g.
+// "relative major/minor, half-time, pitched +1 semitone".
+func (t Transition) Name() string {
+	name := t.Move
+	if family := TempoFamily(t.Multiplier); family != "" {
+		name += ", " + family
+	}
+	switch t.Pitch {
+	case 0:
+		return name
+	case 1, -1:
+		return fmt.Sprintf("%s, pitched %+d semitone", name, t.Pitch)
+	default:
+		return fmt.Sprintf("%s, pitched %+d semitones", name, t.Pitch)
+	}
+}
+
+// String explains the transition, e.g. "energy boost +2, pitched +1
+// semitone, detuned -0.08, -5.2% BPM".
+func (t Transition) String() string {
+	if !t.IsCompatible() {
+		return "no compatible move"
+	}
+	// Rounded first, so -0.04 shows as +0.0 rather than -0.0
+	bpmDelta := math.Round(t.BPMDelta*10)/10 + 0
+	if math.Abs(t.Detune) >= 0.005 {
+		return fmt.Sprintf("%s, detuned %+.2f, %+.1f%% BPM", t.Name(), t.Detune, bpmDelta)
+	}
+	return fmt.Sprintf("%s, %+.1f%% BPM", t.Name(), bpmDelta)
+}
+
+// Suggestion is a suggested or generated track along with the transition into
+// it from the track before.
+type Suggestion interface {
+	Item
+	GetTransition() Transition
+}
@@ -1,9 +1,7 @@
 package models
 
 import (
-	"log"
-	"regexp"
-	"strconv"
+	"fmt"
 	"strings"
 
 	"github.com/xdave/keyid/interfaces"
@@ -36,42 +34,19 @@ var CamelotKeys map[string]interfaces.Scale = map[string]interfaces.Scale{
 	"12B": NewKey("12B"),
 }
 
+// IsCamelotKey tells if key is a Camelot key and nothing else, from 1A to
+// 12B.
 func IsCamelotKey(key string) bool {
-	r := regexp.MustCompile("[0-9]{1,2}[AB]")
-	return r.Match([]byte(key))
+	_, ok := parseCamelot(strings.TrimSpace(key))
+	return ok
 }
 
-func ParseCamelotKey(key string) *CamelotScale {
-	camelotKey := &CamelotScale{}
-	//Make sure key is uppercased
-
-	if !IsCamelotKey(strings.ToUpper(key)) {
-		// convert keys to camelot
-		if scale, ok := PitchToCamelot[key]; ok {
-			key = scale
-		} else {
-			log.Default().Printf("%s is not a valid camelot key", key)
-			return &CamelotScale{Index: 0, Kind: interfaces.Major}
-		}
-	}
-
-	r := regexp.MustCompile(`(?P<Number>\d{1,2})(?P<Letter>[AB]{1})`)
-	match := r.FindStringSubmatch(key)
-	for i, name := range r.SubexpNames() {
-		if i > 0 && i <= len(match) {
-			switch name {
-			case "Number":
-				{
-					n, _ := strconv.Atoi(match[i])
-					camelotKey.Index = n
-				}
-			case "Letter":
-				{
-					camelotKey.Kind = interfaces.ScaleKind(rune([]byte(match[i])[0]))
-				}
-			}
-		}
+// ParseCamelotKey reads a key in Camelot notation only, see ParseKey for the
+// others.
+func ParseCamelotKey(key string) (*CamelotScale, error) {
+	scale, ok := parseCamelot(strings.TrimSpace(key))
+	if !ok {
+		return nil, fmt.Errorf("%s is not a valid camelot key", key)
 	}
-
-	return camelotKey
+	return scale, nil
 }
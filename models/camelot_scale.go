@@ -6,6 +6,9 @@ import (
 	"github.com/xdave/keyid/interfaces"
 )
 
+// CamelotScale is a key on the Camelot wheel. The moves round the wheel are
+// meant for major and minor keys, RuleSet.Match matches modes by the notes
+// they share instead.
 type CamelotScale struct {
 	Index int
 	Kind  interfaces.ScaleKind
@@ -13,8 +16,14 @@ type CamelotScale struct {
 
 type ScaleTransition func() interfaces.Scale
 
+// NewKey reads a key in any notation, see ParseKey. Missing and unreadable
+// keys are an UnknownScale.
 func NewKey(key string) interfaces.Scale {
-	return ParseCamelotKey(key)
+	scale, err := ParseKey(key)
+	if err != nil {
+		return &UnknownScale{Raw: key}
+	}
+	return scale
 }
 
 func (key *CamelotScale) GetIndex() int {
@@ -36,7 +45,12 @@ func (key *CamelotScale) SwapKind() interfaces.Scale {
 	return &CamelotScale{Index: key.Index, Kind: key.Kind.Swap()}
 }
 
+// String is the Camelot key, or the tonic and mode of a mode, e.g. D dorian,
+// which ParseKey both read back.
 func (key *CamelotScale) String() string {
+	if key.Kind.IsMode() {
+		return sharpNotes[tonicPitch(key)] + " " + key.Kind.Name()
+	}
 	return fmt.Sprintf("%d%s", key.Index, key.Kind.String())
 }
 
@@ -71,19 +85,11 @@ func (key *CamelotScale) MajorToMinor() interfaces.Scale {
 }
 
 func (key *CamelotScale) IsEqual(other interfaces.Scale) bool {
-	return key.Index == other.GetIndex() && key.Kind == other.GetKind()
+	return other.IsKnown() && key.Index == other.GetIndex() && key.Kind == other.GetKind()
 }
 
-func (key *CamelotScale) IsCompatible(other interfaces.Scale) bool {
-	return key.IsEqual(other) ||
-		key.IsEqual(other.MajorToMinor()) ||
-		key.IsEqual(other.Horizontal(1)) ||
-		key.IsEqual(other.Horizontal(-1)) ||
-		key.IsEqual(other.Diagonal()) ||
-		key.IsEqual(other.Vertical()) ||
-		key.IsEqual(other.Horizontal(-3)) || // Pay attention -3
-		key.IsEqual(other.Horizontal(+9)) || // Pay attention +9
-		key.IsEqual(other.Horizontal(2)) || // Energy Boost
-		key.IsEqual(other.Horizontal(-5)) || // Jaws mix/Armin Van Buuren variation
-		key.IsEqual(other.FlatToMinor()) // Can't remember where I found this one, lol
+// IsKnown is false for the zero CamelotScale, ParseKey only returns keys on
+// the wheel.
+func (key *CamelotScale) IsKnown() bool {
+	return key.Index >= 1 && key.Index <= 12
 }
@@ -0,0 +1,189 @@
+package models
+
+import (
+	"math/rand"
+	"sort"
+
+	"github.com/xdave/keyid/interfaces"
+)
+
+// Collection is an ordered list of any type. Operations that produce another
+// type, like Map and Reduce, are functions since Go methods can't have type
+// parameters of their own.
+type Collection[T any] struct {
+	items []T
+}
+
+func NewCollection[T any](items ...T) *Collection[T] {
+	return &Collection[T]{
+		items: items,
+	}
+}
+
+// CollectionOf copies the items of an Item collection into a typed one.
+func CollectionOf(c interfaces.Collection) *Collection[interfaces.Item] {
+	return NewCollection(append([]interfaces.Item{}, c.Items()...)...)
+}
+
+func (c *Collection[T]) Append(items ...T) {
+	c.items = append(c.items, items...)
+}
+
+func (c *Collection[T]) ForEach(fn func(i T)) {
+	for _, item := range c.items {
+		fn(item)
+	}
+}
+
+func (c *Collection[T]) Len() int {
+	return len(c.items)
+}
+
+func (c *Collection[T]) IsEmpty() bool {
+	return c.Len() == 0
+}
+
+func (c *Collection[T]) First() T {
+	return c.Get(0)
+}
+
+func (c *Collection[T]) Last() T {
+	return c.Get(c.Len() - 1)
+}
+
+// Get returns the zero value of T when index is out of range.
+func (c *Collection[T]) Get(index int) T {
+	if index < 0 || index >= len(c.items) {
+		var zero T
+		return zero
+	}
+	return c.items[index]
+}
+
+func (c *Collection[T]) Find(predicate func(i T) bool) T {
+	for _, item := range c.items {
+		if predicate(item) {
+			return item
+		}
+	}
+	var zero T
+	return zero
+}
+
+func (c *Collection[T]) FindIndex(predicate func(i T) bool) int {
+	for i, v := range c.items {
+		if predicate(v) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (c *Collection[T]) Items() []T {
+	return c.items
+}
+
+func (c *Collection[T]) RandomShuffle() {
+	rand.Shuffle(len(c.items), func(i, j int) {
+		c.items[i], c.items[j] = c.items[j], c.items[i]
+	})
+}
+
+// Filter returns the items the filter accepts in a new collection.
+func (c *Collection[T]) Filter(filter func(i T) bool) *Collection[T] {
+	newItems := []T{}
+	for _, item := range c.items {
+		if filter(item) {
+			newItems = append(newItems, item)
+		}
+	}
+	return NewCollection(newItems...)
+}
+
+// SortWith returns a sorted copy, keeping the order of items that compare
+// equal.
+func (c *Collection[T]) SortWith(comparator func(i, j T) bool) *Collection[T] {
+	newItems := append([]T{}, c.items...)
+	sort.SliceStable(newItems, func(i, j int) bool {
+		return comparator(newItems[i], newItems[j])
+	})
+	return NewCollection(newItems...)
+}
+
+// Partition splits the items into the ones the predicate accepts and the
+// rest, both in their original order.
+func (c *Collection[T]) Partition(predicate func(i T) bool) (*Collection[T], *Collection[T]) {
+	matching := []T{}
+	rest := []T{}
+	for _, item := range c.items {
+		if predicate(item) {
+			matching = append(matching, item)
+		} else {
+			rest = append(rest, item)
+		}
+	}
+	return NewCollection(matching...), NewCollection(rest...)
+}
+
+// Chunk splits the items into collections of size items, the last one holding
+// whatever is left. A size below 1 returns a single chunk.
+func (c *Collection[T]) Chunk(size int) []*Collection[T] {
+	if size < 1 {
+		size = len(c.items)
+	}
+	chunks := []*Collection[T]{}
+	for start := 0; start < len(c.items); start += size {
+		end := min(start+size, len(c.items))
+		chunks = append(chunks, NewCollection(append([]T{}, c.items[start:end]...)...))
+	}
+	return chunks
+}
+
+func Map[T any, U any](c *Collection[T], mapper func(i T) U) *Collection[U] {
+	newItems := make([]U, 0, c.Len())
+	for _, item := range c.items {
+		newItems = append(newItems, mapper(item))
+	}
+	return NewCollection(newItems...)
+}
+
+// Reduce folds the items into an accumulator of any type, so an empty
+// collection reduces to initial.
+func Reduce[T any, A any](c *Collection[T], initial A, reducer func(acc A, i T) A) A {
+	acc := initial
+	for _, item := range c.items {
+		acc = reducer(acc, item)
+	}
+	return acc
+}
+
+// GroupBy collects the items by key, keeping their order within each group.
+func GroupBy[T any, K comparable](c *Collection[T], key func(i T) K) map[K]*Collection[T] {
+	groups := make(map[K]*Collection[T])
+	for _, item := range c.items {
+		k := key(item)
+		group, ok := groups[k]
+		if !ok {
+			group = NewCollection[T]()
+			groups[k] = group
+		}
+		group.Append(item)
+	}
+	return groups
+}
+
+type Pair[T any, U any] struct {
+	First  T
+	Second U
+}
+
+// Zip pairs the items of both collections by position, stopping at the end of
+// the shorter one.
+func Zip[T any, U any](a *Collection[T], b *Collection[U]) *Collection[Pair[T, U]] {
+	n := min(a.Len(), b.Len())
+	pairs := make([]Pair[T, U], 0, n)
+	for i := 0; i < n; i++ {
+		pairs = append(pairs, Pair[T, U]{First: a.items[i], Second: b.items[i]})
+	}
+	return NewCollection(pairs...)
+}
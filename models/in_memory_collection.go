@@ -1,20 +1,20 @@
 package models
 
 import (
-	"math/rand"
 	"slices"
-	"sort"
 
 	"github.com/xdave/keyid/interfaces"
 )
 
+// InMemoryCollection is the Item collection, built on Collection for the
+// operations that don't need to compare items.
 type InMemoryCollection struct {
-	items []interfaces.Item
+	*Collection[interfaces.Item]
 }
 
 func NewInMemoryCollection(items ...interfaces.Item) interfaces.Collection {
 	return &InMemoryCollection{
-		items: items,
+		Collection: NewCollection(items...),
 	}
 }
 
@@ -26,7 +26,7 @@ func (c *InMemoryCollection) Contains(item interfaces.Item) bool {
 
 func (c *InMemoryCollection) Add(item interfaces.Item) {
 	if !c.Contains(item) {
-		c.items = append(c.items, item)
+		c.Append(item)
 	}
 }
 
@@ -38,7 +38,7 @@ func (c *InMemoryCollection) Remove(toRemove interfaces.Item) interfaces.Item {
 	newItems := []interfaces.Item{}
 
 	for _, item := range c.items {
-		if item.Equals(toRemove) {
+		if !item.Equals(toRemove) {
 			newItems = append(newItems, item)
 		}
 	}
@@ -52,114 +52,42 @@ func (c *InMemoryCollection) MoveTo(item interfaces.Item, collection interfaces.
 	c.Remove(item)
 }
 
-func (c *InMemoryCollection) ForEach(fn func(i interfaces.Item)) {
-	for _, item := range c.items {
-		fn(item)
-	}
-}
-
 func (c *InMemoryCollection) Map(mapper func(i interfaces.Item) interfaces.Item) interfaces.Collection {
-	newItems := []interfaces.Item{}
-	for _, item := range c.items {
-		newItems = append(newItems, mapper(item))
-	}
-	return NewInMemoryCollection(newItems...)
+	return &InMemoryCollection{Collection: Map(c.Collection, mapper)}
 }
 
 func (c *InMemoryCollection) Filter(filter func(i interfaces.Item) bool) interfaces.Collection {
-	newItems := []interfaces.Item{}
-	for _, item := range c.items {
-		if filter(item) {
-			newItems = append(newItems, item)
-		}
-	}
-	return NewInMemoryCollection(newItems...)
+	return &InMemoryCollection{Collection: c.Collection.Filter(filter)}
 }
 
+// Reduce starts from the first item, so an empty collection reduces to nil.
 func (c *InMemoryCollection) Reduce(reducer func(i interfaces.Item, acc interfaces.Item) interfaces.Item) interfaces.Item {
-	acc := c.items[0]
-	for _, item := range c.items[1:] {
-		acc = reducer(item, acc)
-	}
-	return acc
-}
-
-func (c *InMemoryCollection) Len() int {
-	return len(c.items)
+	return reduceItems(c.Collection, reducer)
 }
 
-func (c *InMemoryCollection) IsEmpty() bool {
-	return c.Len() == 0
-}
-
-func (c *InMemoryCollection) First() interfaces.Item {
+// reduceItems adapts the Item collections' Reduce, which has no initial
+// accumulator, to the generic one.
+func reduceItems(c *Collection[interfaces.Item], reducer func(i interfaces.Item, acc interfaces.Item) interfaces.Item) interfaces.Item {
 	if c.IsEmpty() {
 		return nil
 	}
-	return c.items[0]
-}
-
-func (c *InMemoryCollection) Last() interfaces.Item {
-	if c.IsEmpty() {
-		return nil
-	}
-	return c.items[len(c.items)-1]
-}
-
-func (c *InMemoryCollection) Get(index int) interfaces.Item {
-	if index < 0 || index >= len(c.items) {
-		return nil
-	}
-	return c.items[index]
+	return Reduce(NewCollection(c.items[1:]...), c.items[0], func(acc interfaces.Item, i interfaces.Item) interfaces.Item {
+		return reducer(i, acc)
+	})
 }
 
 func (c *InMemoryCollection) IndexOf(item interfaces.Item) int {
-	for i, v := range c.items {
-		if item.Equals(v) {
-			return i
-		}
-	}
-	return -1
-}
-
-func (c *InMemoryCollection) Find(predicate func(i interfaces.Item) bool) interfaces.Item {
-	for _, item := range c.items {
-		if predicate(item) {
-			return item
-		}
-	}
-	return nil
-}
-
-func (c *InMemoryCollection) FindIndex(predicate func(i interfaces.Item) bool) int {
-	for i, v := range c.items {
-		if predicate(v) {
-			return i
-		}
-	}
-	return -1
+	return c.FindIndex(item.Equals)
 }
 
+// SortWith returns a sorted copy without duplicates, keeping the order of
+// items that compare equal.
 func (c *InMemoryCollection) SortWith(comparator func(i, j interfaces.Item) bool) interfaces.Collection {
-	newCollection := &InMemoryCollection{}
+	newCollection := &InMemoryCollection{Collection: NewCollection[interfaces.Item]()}
 
 	for _, item := range c.items {
 		newCollection.Add(item)
 	}
 
-	sort.Slice(newCollection.items, func(i, j int) bool {
-		return comparator(newCollection.Get(i), newCollection.Get(j))
-	})
-
-	return newCollection
-}
-
-func (c *InMemoryCollection) Items() []interfaces.Item {
-	return c.items
-}
-
-func (c *InMemoryCollection) RandomShuffle() {
-	rand.Shuffle(len(c.items), func(i, j int) {
-		c.items[i], c.items[j] = c.items[j], c.items[i]
-	})
+	return &InMemoryCollection{Collection: newCollection.Collection.SortWith(comparator)}
 }
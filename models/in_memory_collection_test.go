@@ -0,0 +1,247 @@
+package models_test
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+	"testing/quick"
+
+	"github.com/xdave/keyid/client"
+	"github.com/xdave/keyid/interfaces"
+	"github.com/xdave/keyid/models"
+)
+
+// collections are the Item collections, which all keep the same invariants.
+var collections = []struct {
+	name string
+	new  func(items ...interfaces.Item) interfaces.Collection
+}{
+	{"InMemoryCollection", models.NewInMemoryCollection},
+	{"IndexedCollection", func(items ...interfaces.Item) interfaces.Collection {
+		return models.NewIndexedCollection(items...)
+	}},
+	{"SyncCollection", func(items ...interfaces.Item) interfaces.Collection {
+		return models.NewSyncCollection(items...)
+	}},
+}
+
+func track(id int, bpm float64) interfaces.Item {
+	return &client.Track{
+		ID:    fmt.Sprint(id),
+		BPM:   bpm,
+		Scale: models.NewKey("8A"),
+		Title: fmt.Sprint("track ", id),
+		Tags:  []string{},
+	}
+}
+
+// tracks builds a track for each id, keeping duplicates, with a BPM from a
+// handful of values so sorting has ties.
+func tracks(ids []uint8) []interfaces.Item {
+	items := []interfaces.Item{}
+	for _, id := range ids {
+		items = append(items, track(int(id%32), float64(120+id%4)))
+	}
+	return items
+}
+
+func ids(c interfaces.Collection) []string {
+	ids := []string{}
+	for _, item := range c.Items() {
+		ids = append(ids, item.GetID())
+	}
+	return ids
+}
+
+// uniqueIDs returns the ids of the items in the order they first appear.
+func uniqueIDs(items []interfaces.Item) []string {
+	ids := []string{}
+	for _, item := range items {
+		if !slices.Contains(ids, item.GetID()) {
+			ids = append(ids, item.GetID())
+		}
+	}
+	return ids
+}
+
+func TestCollectionAdd(t *testing.T) {
+	for _, tc := range collections {
+		t.Run(tc.name, func(t *testing.T) {
+			property := func(added []uint8) bool {
+				items := tracks(added)
+				c := tc.new()
+				for _, item := range items {
+					c.Add(item)
+				}
+				want := uniqueIDs(items)
+				if !slices.Equal(ids(c), want) || c.Len() != len(want) || c.IsEmpty() != (len(want) == 0) {
+					return false
+				}
+				for i, id := range want {
+					item := c.Get(i)
+					if item == nil || item.GetID() != id || !c.Contains(item) || c.IndexOf(item) != i {
+						return false
+					}
+				}
+				return true
+			}
+			if err := quick.Check(property, nil); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestCollectionRemove(t *testing.T) {
+	for _, tc := range collections {
+		t.Run(tc.name, func(t *testing.T) {
+			property := func(added []uint8, removed []uint8) bool {
+				c := tc.new()
+				for _, item := range tracks(added) {
+					c.Add(item)
+				}
+				gone := tracks(removed)
+				for _, item := range gone {
+					if c.Remove(item) != item {
+						return false
+					}
+				}
+
+				want := slices.DeleteFunc(uniqueIDs(tracks(added)), func(id string) bool {
+					return slices.Contains(uniqueIDs(gone), id)
+				})
+				if !slices.Equal(ids(c), want) {
+					return false
+				}
+				for _, item := range gone {
+					if c.Contains(item) || c.IndexOf(item) != -1 {
+						return false
+					}
+				}
+				for i, item := range c.Items() {
+					if !c.Contains(item) || c.IndexOf(item) != i {
+						return false
+					}
+				}
+				return true
+			}
+			if err := quick.Check(property, nil); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestCollectionMoveTo(t *testing.T) {
+	for _, tc := range collections {
+		t.Run(tc.name, func(t *testing.T) {
+			from := tc.new(track(1, 120), track(2, 121))
+			to := tc.new()
+			from.MoveTo(track(1, 120), to)
+
+			if got := ids(from); !slices.Equal(got, []string{"2"}) {
+				t.Errorf("from = %v, want [2]", got)
+			}
+			if got := ids(to); !slices.Equal(got, []string{"1"}) {
+				t.Errorf("to = %v, want [1]", got)
+			}
+		})
+	}
+}
+
+func TestCollectionSortWith(t *testing.T) {
+	byBPM := func(a, b interfaces.Item) bool {
+		return a.GetBPM() < b.GetBPM()
+	}
+	for _, tc := range collections {
+		t.Run(tc.name, func(t *testing.T) {
+			property := func(added []uint8) bool {
+				c := tc.new()
+				for _, item := range tracks(added) {
+					c.Add(item)
+				}
+				before := ids(c)
+				sorted := c.SortWith(byBPM)
+
+				// A copy, the collection keeps its order
+				if !slices.Equal(ids(c), before) || sorted.Len() != c.Len() {
+					return false
+				}
+				items := sorted.Items()
+				for i := 1; i < len(items); i++ {
+					if items[i].GetBPM() < items[i-1].GetBPM() {
+						return false
+					}
+					// Ties keep the order they had
+					if items[i].GetBPM() == items[i-1].GetBPM() &&
+						slices.Index(before, items[i].GetID()) < slices.Index(before, items[i-1].GetID()) {
+						return false
+					}
+				}
+				return true
+			}
+			if err := quick.Check(property, nil); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestCollectionReduce(t *testing.T) {
+	sumBPM := func(i interfaces.Item, acc interfaces.Item) interfaces.Item {
+		return track(0, acc.GetBPM()+i.GetBPM())
+	}
+	tests := []struct {
+		name  string
+		items []interfaces.Item
+		want  interfaces.Item
+	}{
+		{"empty", nil, nil},
+		{"one item", []interfaces.Item{track(1, 120)}, track(1, 120)},
+		{"many items", []interfaces.Item{track(1, 120), track(2, 121), track(3, 122)}, track(0, 363)},
+	}
+	for _, tc := range collections {
+		for _, tt := range tests {
+			t.Run(tc.name+"/"+tt.name, func(t *testing.T) {
+				got := tc.new(tt.items...).Reduce(sumBPM)
+				if tt.want == nil {
+					if got != nil {
+						t.Errorf("Reduce() = %v, want nil", got)
+					}
+					return
+				}
+				if got == nil || got.GetBPM() != tt.want.GetBPM() {
+					t.Errorf("Reduce() = %v, want BPM %v", got, tt.want.GetBPM())
+				}
+			})
+		}
+	}
+}
+
+func TestCollectionFilterAndMap(t *testing.T) {
+	for _, tc := range collections {
+		t.Run(tc.name, func(t *testing.T) {
+			c := tc.new(track(1, 120), track(2, 126), track(3, 124))
+			fast := c.Filter(func(i interfaces.Item) bool {
+				return i.GetBPM() > 122
+			})
+			if got := ids(fast); !slices.Equal(got, []string{"2", "3"}) {
+				t.Errorf("Filter() = %v, want [2 3]", got)
+			}
+
+			doubled := c.Map(func(i interfaces.Item) interfaces.Item {
+				return &client.Track{ID: i.GetID(), BPM: i.GetBPM() * 2, Scale: i.GetScale()}
+			})
+			bpms := []float64{}
+			doubled.ForEach(func(i interfaces.Item) {
+				bpms = append(bpms, i.GetBPM())
+			})
+			if !slices.Equal(bpms, []float64{240, 252, 248}) {
+				t.Errorf("Map() BPMs = %v, want [240 252 248]", bpms)
+			}
+			if c.Len() != 3 {
+				t.Errorf("Filter and Map changed the collection to %v", ids(c))
+			}
+		})
+	}
+}
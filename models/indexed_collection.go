@@ -0,0 +1,181 @@
+package models
+
+import (
+	"math"
+	"slices"
+	"sort"
+
+	"github.com/xdave/keyid/interfaces"
+)
+
+// Width of the BPM ranges items are grouped in
+const bpmBucketSize = 2.0
+
+type indexKey struct {
+	scale  string
+	bucket int
+}
+
+// IndexedCollection keeps its items indexed by ID, so Contains and IndexOf
+// don't scan, and by Camelot key and BPM once Candidates is first called, so
+// it only visits the keys and BPM ranges asked for. Items are unique by ID,
+// like Add already makes them.
+type IndexedCollection struct {
+	*Collection[interfaces.Item]
+	positions map[string]int
+	// nil until the first Candidates call, most collections never need them
+	scales  map[string]interfaces.Scale
+	buckets map[indexKey][]interfaces.Item
+}
+
+func NewIndexedCollection(items ...interfaces.Item) *IndexedCollection {
+	c := &IndexedCollection{
+		Collection: NewCollection(make([]interfaces.Item, 0, len(items))...),
+		positions:  make(map[string]int, len(items)),
+	}
+	for _, item := range items {
+		c.Add(item)
+	}
+	return c
+}
+
+func bpmBucket(bpm float64) int {
+	return int(math.Floor(bpm / bpmBucketSize))
+}
+
+func indexKeyOf(item interfaces.Item) indexKey {
+	return indexKey{scale: item.GetScale().String(), bucket: bpmBucket(item.GetBPM())}
+}
+
+func (c *IndexedCollection) buildIndex() {
+	c.scales = make(map[string]interfaces.Scale)
+	c.buckets = make(map[indexKey][]interfaces.Item)
+	for _, item := range c.items {
+		c.indexItem(item)
+	}
+}
+
+func (c *IndexedCollection) indexItem(item interfaces.Item) {
+	key := indexKeyOf(item)
+	if _, ok := c.scales[key.scale]; !ok {
+		c.scales[key.scale] = item.GetScale()
+	}
+	c.buckets[key] = append(c.buckets[key], item)
+}
+
+// reindexPositions is needed whenever items move, which is rare compared to
+// lookups.
+func (c *IndexedCollection) reindexPositions() {
+	for i, item := range c.items {
+		c.positions[item.GetID()] = i
+	}
+}
+
+func (c *IndexedCollection) Contains(item interfaces.Item) bool {
+	_, ok := c.positions[item.GetID()]
+	return ok
+}
+
+func (c *IndexedCollection) Add(item interfaces.Item) {
+	if c.Contains(item) {
+		return
+	}
+	c.positions[item.GetID()] = len(c.items)
+	c.Append(item)
+	if c.buckets != nil {
+		c.indexItem(item)
+	}
+}
+
+func (c *IndexedCollection) Remove(toRemove interfaces.Item) interfaces.Item {
+	position, ok := c.positions[toRemove.GetID()]
+	if !ok {
+		return toRemove
+	}
+	item := c.items[position]
+	c.items = slices.Delete(c.items, position, position+1)
+	delete(c.positions, item.GetID())
+	c.reindexPositions()
+
+	if c.buckets != nil {
+		key := indexKeyOf(item)
+		c.buckets[key] = slices.DeleteFunc(c.buckets[key], item.Equals)
+		if len(c.buckets[key]) == 0 {
+			delete(c.buckets, key)
+		}
+	}
+
+	return toRemove
+}
+
+func (c *IndexedCollection) MoveTo(item interfaces.Item, collection interfaces.Collection) {
+	collection.Add(item)
+	c.Remove(item)
+}
+
+// Candidates probes the buckets of the matching keys only, sorting the few
+// items found back into collection order.
+func (c *IndexedCollection) Candidates(queries ...interfaces.CandidateQuery) []interfaces.Item {
+	if c.buckets == nil {
+		c.buildIndex()
+	}
+
+	found := []int{}
+	for _, query := range queries {
+		for name, scale := range c.scales {
+			if !query.KeyFilter(scale) {
+				continue
+			}
+			for bucket := bpmBucket(query.MinBPM); bucket <= bpmBucket(query.MaxBPM); bucket++ {
+				for _, item := range c.buckets[indexKey{scale: name, bucket: bucket}] {
+					if item.GetBPM() >= query.MinBPM && item.GetBPM() <= query.MaxBPM {
+						found = append(found, c.positions[item.GetID()])
+					}
+				}
+			}
+		}
+	}
+	sort.Ints(found)
+
+	candidates := make([]interfaces.Item, 0, len(found))
+	for i, position := range found {
+		if i > 0 && found[i-1] == position {
+			// Selected by more than one query
+			continue
+		}
+		candidates = append(candidates, c.items[position])
+	}
+	return candidates
+}
+
+func (c *IndexedCollection) Map(mapper func(i interfaces.Item) interfaces.Item) interfaces.Collection {
+	return NewIndexedCollection(Map(c.Collection, mapper).Items()...)
+}
+
+func (c *IndexedCollection) Filter(filter func(i interfaces.Item) bool) interfaces.Collection {
+	return NewIndexedCollection(c.Collection.Filter(filter).Items()...)
+}
+
+// Reduce starts from the first item, so an empty collection reduces to nil.
+func (c *IndexedCollection) Reduce(reducer func(i interfaces.Item, acc interfaces.Item) interfaces.Item) interfaces.Item {
+	return reduceItems(c.Collection, reducer)
+}
+
+func (c *IndexedCollection) IndexOf(item interfaces.Item) int {
+	position, ok := c.positions[item.GetID()]
+	if !ok {
+		return -1
+	}
+	return position
+}
+
+// SortWith returns a sorted copy, keeping the order of items that compare
+// equal.
+func (c *IndexedCollection) SortWith(comparator func(i, j interfaces.Item) bool) interfaces.Collection {
+	return NewIndexedCollection(c.Collection.SortWith(comparator).Items()...)
+}
+
+func (c *IndexedCollection) RandomShuffle() {
+	c.Collection.RandomShuffle()
+	c.reindexPositions()
+}
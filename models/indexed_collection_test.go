@@ -0,0 +1,54 @@
+package models_test
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+	"testing/quick"
+
+	"github.com/xdave/keyid/client"
+	"github.com/xdave/keyid/interfaces"
+	"github.com/xdave/keyid/models"
+)
+
+// Candidates selects the same items, in the same order, as filtering every
+// item with the queries, however items were added and removed.
+func TestIndexedCollectionCandidates(t *testing.T) {
+	keys := []string{"8A", "8B", "9A", "D dorian", ""}
+	property := func(added []uint16, removed []uint8, minBPM uint8, width uint8, key uint8) bool {
+		c := models.NewIndexedCollection()
+		// Candidates builds the index, later changes have to keep it up to date
+		c.Candidates()
+		for _, n := range added {
+			c.Add(&client.Track{
+				ID:    fmt.Sprint(n % 64),
+				BPM:   100 + float64(n%400)/10,
+				Scale: models.NewKey(keys[int(n)%len(keys)]),
+				Tags:  []string{},
+			})
+		}
+		for _, n := range removed {
+			c.Remove(&client.Track{ID: fmt.Sprint(n % 64)})
+		}
+
+		want := keys[int(key)%len(keys)]
+		query := interfaces.CandidateQuery{
+			MinBPM: 100 + float64(minBPM%40),
+			MaxBPM: 100 + float64(minBPM%40) + float64(width%10),
+			KeyFilter: func(scale interfaces.Scale) bool {
+				return scale.String() == models.NewKey(want).String()
+			},
+		}
+		selects := func(i interfaces.Item) bool {
+			return i.GetBPM() >= query.MinBPM && i.GetBPM() <= query.MaxBPM && query.KeyFilter(i.GetScale())
+		}
+		// The same query twice still selects each item once
+		found := c.Candidates(query, query)
+		return slices.EqualFunc(found, c.Filter(selects).Items(), func(a, b interfaces.Item) bool {
+			return a.Equals(b)
+		})
+	}
+	if err := quick.Check(property, nil); err != nil {
+		t.Error(err)
+	}
+}
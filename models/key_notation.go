@@ -0,0 +1,209 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/xdave/keyid/interfaces"
+)
+
+// KeyNotation reads and writes keys in one of the ways DJ software and
+// taggers write them.
+type KeyNotation struct {
+	Name string
+	// parse returns false when key isn't in this notation
+	parse  func(key string) (*CamelotScale, bool)
+	format func(key interfaces.Scale) string
+	// modeNotes spell the tonic of modes, which every notation writes out,
+	// e.g. D dorian
+	modeNotes []string
+}
+
+var (
+	// CamelotNotation is Mixed In Key's, e.g. 8A
+	CamelotNotation = &KeyNotation{Name: "camelot", parse: parseCamelot, format: func(key interfaces.Scale) string {
+		return fmt.Sprintf("%d%s", key.GetIndex(), key.GetKind())
+	}, modeNotes: sharpNotes}
+	// OpenKeyNotation is Traktor's, e.g. 1m, which is 8A shifted by 5
+	OpenKeyNotation = &KeyNotation{Name: "openkey", parse: parseOpenKey, format: func(key interfaces.Scale) string {
+		return fmt.Sprintf("%d%s", interfaces.ModCyclic(key.GetIndex()+5, 12), openKeyKinds[key.GetKind()])
+	}, modeNotes: sharpNotes}
+	// SharpNotation and FlatNotation are the musical key, e.g. F#m or Gbm
+	SharpNotation = &KeyNotation{Name: "sharps", parse: parseMusical, format: func(key interfaces.Scale) string {
+		return tonic(key, sharpNotes, sharpNotes) + kindSuffix(key, "", "m")
+	}, modeNotes: sharpNotes}
+	FlatNotation = &KeyNotation{Name: "flats", parse: parseMusical, format: func(key interfaces.Scale) string {
+		return tonic(key, flatNotes, flatNotes) + kindSuffix(key, "", "m")
+	}, modeNotes: flatNotes}
+	// LongNotation spells the musical key out the way sheet music usually
+	// does, e.g. Bb major or C# minor
+	LongNotation = &KeyNotation{Name: "long", parse: parseMusical, format: func(key interfaces.Scale) string {
+		return tonic(key, majorNotes, minorNotes) + kindSuffix(key, " major", " minor")
+	}, modeNotes: majorNotes}
+)
+
+// KeyNotations are every notation, the first one that parses a key wins.
+var KeyNotations = []*KeyNotation{CamelotNotation, OpenKeyNotation, SharpNotation, FlatNotation, LongNotation}
+
+// KeyNotationNames returns the names of the notations.
+func KeyNotationNames() []string {
+	names := []string{}
+	for _, notation := range KeyNotations {
+		names = append(names, notation.Name)
+	}
+	return names
+}
+
+// ParseKeyNotation returns the notation with the given name.
+func ParseKeyNotation(name string) (*KeyNotation, error) {
+	for _, notation := range KeyNotations {
+		if notation.Name == name {
+			return notation, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown key notation '%s' (key notations: %s)", name, strings.Join(KeyNotationNames(), ", "))
+}
+
+// Format writes key in the notation. Unknown keys have no name in any
+// notation and are written "?", and modes, which Camelot and Open Key have no
+// names for, are their tonic and mode.
+func (n *KeyNotation) Format(key interfaces.Scale) string {
+	if !key.IsKnown() {
+		return key.String()
+	}
+	if key.GetKind().IsMode() {
+		return n.modeNotes[tonicPitch(key)] + " " + key.GetKind().Name()
+	}
+	return n.format(key)
+}
+
+// ParseKey reads a key in any notation: Camelot (8A), Open Key (1m), musical
+// with sharps or flats (Am, F#, Gbm), long names (A minor, F# maj) or modes
+// (D dorian).
+func ParseKey(key string) (*CamelotScale, error) {
+	trimmed := strings.TrimSpace(key)
+	for _, notation := range KeyNotations {
+		if scale, ok := notation.parse(trimmed); ok {
+			return scale, nil
+		}
+	}
+	return nil, fmt.Errorf("%s is not a valid key", key)
+}
+
+var (
+	camelotPattern = regexp.MustCompile(`^(?i)(\d{1,2})([AB])$`)
+	openKeyPattern = regexp.MustCompile(`^(?i)(\d{1,2})([MD])$`)
+	// A note, an accidental and major or minor spelled any way, or a mode,
+	// e.g. "Abm", "F# maj", "A minor" or "D dorian". The note, the flat and
+	// the short minor "m" are case sensitive, so "AM" and "bb" are no keys
+	// rather than maybe A minor and Bb major. Spelled out names aren't.
+	musicalPattern = regexp.MustCompile(`^([A-G])\s*([#♯b♭]?)\s*(m|(?i:min|minor|maj|major|ionian|dorian|phrygian|lydian|mixolydian|aeolian|locrian))?$`)
+)
+
+// modeKinds are the modes by name, including the major and minor ones
+var modeKinds = map[string]interfaces.ScaleKind{
+	"ionian":     interfaces.Ionian,
+	"dorian":     interfaces.Dorian,
+	"phrygian":   interfaces.Phrygian,
+	"lydian":     interfaces.Lydian,
+	"mixolydian": interfaces.Mixolydian,
+	"aeolian":    interfaces.Aeolian,
+	"locrian":    interfaces.Locrian,
+}
+
+var openKeyKinds = map[interfaces.ScaleKind]string{
+	interfaces.Minor: "m",
+	interfaces.Major: "d",
+}
+
+var (
+	sharpNotes = []string{"C", "C#", "D", "D#", "E", "F", "F#", "G", "G#", "A", "A#", "B"}
+	flatNotes  = []string{"C", "Db", "D", "Eb", "E", "F", "Gb", "G", "Ab", "A", "Bb", "B"}
+	majorNotes = []string{"C", "Db", "D", "Eb", "E", "F", "F#", "G", "Ab", "A", "Bb", "B"}
+	minorNotes = []string{"C", "C#", "D", "Eb", "E", "F", "F#", "G", "G#", "A", "Bb", "B"}
+)
+
+func wheelIndex(number string) (int, bool) {
+	n, err := strconv.Atoi(number)
+	return n, err == nil && n >= 1 && n <= 12
+}
+
+func parseCamelot(key string) (*CamelotScale, bool) {
+	match := camelotPattern.FindStringSubmatch(key)
+	if match == nil {
+		return nil, false
+	}
+	index, ok := wheelIndex(match[1])
+	if !ok {
+		return nil, false
+	}
+	return &CamelotScale{Index: index, Kind: interfaces.ScaleKind(strings.ToUpper(match[2])[0])}, true
+}
+
+func parseOpenKey(key string) (*CamelotScale, bool) {
+	match := openKeyPattern.FindStringSubmatch(key)
+	if match == nil {
+		return nil, false
+	}
+	index, ok := wheelIndex(match[1])
+	if !ok {
+		return nil, false
+	}
+	kind := interfaces.Major
+	if strings.EqualFold(match[2], "m") {
+		kind = interfaces.Minor
+	}
+	return &CamelotScale{Index: interfaces.ModCyclic(index+7, 12), Kind: kind}, true
+}
+
+// parseMusical spells the key the way PitchToCamelot does, e.g. "Gbm", to
+// look it up. Modes are found from their tonic instead.
+func parseMusical(key string) (*CamelotScale, bool) {
+	match := musicalPattern.FindStringSubmatch(key)
+	if match == nil {
+		return nil, false
+	}
+	accidental := ""
+	switch match[2] {
+	case "#", "♯":
+		accidental = "#"
+	case "b", "♭":
+		accidental = "b"
+	}
+	name := match[1] + accidental
+	if kind, ok := modeKinds[strings.ToLower(match[3])]; ok {
+		pitch, ok := notePitch(name)
+		if !ok {
+			return nil, false
+		}
+		return keyOnTonic(pitch, kind), true
+	}
+
+	switch strings.ToLower(match[3]) {
+	case "m", "min", "minor":
+		name += "m"
+	}
+	camelot, ok := PitchToCamelot[name]
+	if !ok {
+		return nil, false
+	}
+	return parseCamelot(camelot)
+}
+
+// tonic names the note key is on, see tonicPitch, spelled from minorNotes
+// for minor keys and majorNotes otherwise.
+func tonic(key interfaces.Scale, majorNotes []string, minorNotes []string) string {
+	if key.GetKind() == interfaces.Minor {
+		return minorNotes[tonicPitch(key)]
+	}
+	return majorNotes[tonicPitch(key)]
+}
+
+func kindSuffix(key interfaces.Scale, major string, minor string) string {
+	if key.GetKind() == interfaces.Minor {
+		return minor
+	}
+	return major
+}
@@ -0,0 +1,165 @@
+package models_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/xdave/keyid/interfaces"
+	"github.com/xdave/keyid/models"
+)
+
+// allKeys are the 24 major and minor keys round the wheel.
+func allKeys() []*models.CamelotScale {
+	keys := []*models.CamelotScale{}
+	for index := 1; index <= 12; index++ {
+		for _, kind := range []interfaces.ScaleKind{interfaces.Minor, interfaces.Major} {
+			keys = append(keys, &models.CamelotScale{Index: index, Kind: kind})
+		}
+	}
+	return keys
+}
+
+// Every notation reads back the key it writes, and writes each key its own way.
+func TestKeyNotationRoundTrip(t *testing.T) {
+	modes := []interfaces.ScaleKind{interfaces.Dorian, interfaces.Phrygian, interfaces.Lydian, interfaces.Mixolydian, interfaces.Locrian}
+	for _, notation := range models.KeyNotations {
+		t.Run(notation.Name, func(t *testing.T) {
+			names := map[string]string{}
+			keys := allKeys()
+			for index := 1; index <= 12; index++ {
+				for _, kind := range modes {
+					keys = append(keys, &models.CamelotScale{Index: index, Kind: kind})
+				}
+			}
+			for _, key := range keys {
+				name := notation.Format(key)
+				parsed, err := models.ParseKey(name)
+				if err != nil {
+					t.Errorf("%s writes %v as %q, which doesn't parse: %v", notation.Name, key, name, err)
+					continue
+				}
+				if !parsed.IsEqual(key) {
+					t.Errorf("%s writes %v as %q, which parses as %v", notation.Name, key, name, parsed)
+				}
+				if other, ok := names[name]; ok {
+					t.Errorf("%s writes both %v and %s as %q", notation.Name, key, other, name)
+				}
+				names[name] = key.String()
+			}
+		})
+	}
+}
+
+func TestKeyNotationFormat(t *testing.T) {
+	tests := []struct {
+		key                                   string
+		camelot, openkey, sharps, flats, long string
+	}{
+		{"8A", "8A", "1m", "Am", "Am", "A minor"},
+		{"2B", "2B", "7d", "F#", "Gb", "F# major"},
+		{"11A", "11A", "4m", "F#m", "Gbm", "F# minor"},
+		{"3B", "3B", "8d", "C#", "Db", "Db major"},
+		{"12A", "12A", "5m", "C#m", "Dbm", "C# minor"},
+		{"D dorian", "D dorian", "D dorian", "D dorian", "D dorian", "D dorian"},
+		{"Bb mixolydian", "A# mixolydian", "A# mixolydian", "A# mixolydian", "Bb mixolydian", "Bb mixolydian"},
+		{"", "?", "?", "?", "?", "?"},
+	}
+	for _, tt := range tests {
+		for i, notation := range models.KeyNotations {
+			want := []string{tt.camelot, tt.openkey, tt.sharps, tt.flats, tt.long}[i]
+			if got := notation.Format(models.NewKey(tt.key)); got != want {
+				t.Errorf("%s.Format(%s) = %q, want %q", notation.Name, tt.key, got, want)
+			}
+		}
+	}
+}
+
+// notePitches are the notes PitchToCamelot spells, C being 0.
+var notePitches = map[string]int{
+	"C": 0, "C#": 1, "Db": 1, "D": 2, "D#": 3, "Eb": 3, "E": 4, "F": 5,
+	"F#": 6, "Gb": 6, "G": 7, "G#": 8, "Ab": 8, "A": 9, "A#": 10, "Bb": 10, "B": 11, "Cb": 11,
+}
+
+// Each step round the wheel is a fifth, 7 semitones, up from C major (8B)
+// and A minor (8A).
+func TestPitchToCamelot(t *testing.T) {
+	for name, camelot := range models.PitchToCamelot {
+		note, minor := strings.CutSuffix(name, "m")
+		pitch, ok := notePitches[note]
+		if !ok {
+			t.Errorf("PitchToCamelot has unknown note %q", name)
+			continue
+		}
+		want := fmt.Sprintf("%dB", interfaces.ModCyclic(8+7*pitch, 12))
+		if minor {
+			want = fmt.Sprintf("%dA", interfaces.ModCyclic(8+7*(pitch-9), 12))
+		}
+		if camelot != want {
+			t.Errorf("PitchToCamelot[%q] = %s, want %s", name, camelot, want)
+		}
+		key, err := models.ParseKey(name)
+		if err != nil || key.String() != camelot {
+			t.Errorf("ParseKey(%q) = %v, %v, want %s", name, key, err, camelot)
+		}
+	}
+}
+
+func TestParseKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"8A", "8A"},
+		{"8a", "8A"},
+		{" 12B ", "12B"},
+		{"1m", "8A"},
+		{"7d", "2B"},
+		{"Am", "8A"},
+		{"A", "11B"},
+		{"Gbm", "11A"},
+		{"F♯m", "11A"},
+		{"B♭", "6B"},
+		{"Cb", "1B"},
+		{"A minor", "8A"},
+		{"A MINOR", "8A"},
+		{"F# maj", "2B"},
+		{"C ionian", "8B"},
+		{"A aeolian", "8A"},
+		{"D dorian", "D dorian"},
+		{"Bb mixolydian", "A# mixolydian"},
+		{"B locrian", "B locrian"},
+	}
+	for _, tt := range tests {
+		key, err := models.ParseKey(tt.key)
+		if err != nil || key.String() != tt.want {
+			t.Errorf("ParseKey(%q) = %v, %v, want %s", tt.key, key, err, tt.want)
+		}
+	}
+}
+
+func TestParseKeyRejects(t *testing.T) {
+	for _, key := range []string{
+		"", "?", "0A", "13A", "8C", "13m", "0d", "AM", "am", "bb", "H",
+		"E#", "Fb", "B#m", "B#", "Cbm", "Fbm", "E#m",
+		"E# dorian", "Fb lydian", "B# minor", "Cb locrian", "D dorien",
+	} {
+		if scale, err := models.ParseKey(key); err == nil {
+			t.Errorf("ParseKey(%q) = %v, want an error", key, scale)
+		}
+		if models.NewKey(key).IsKnown() {
+			t.Errorf("NewKey(%q) is known", key)
+		}
+	}
+}
+
+func TestParseKeyNotation(t *testing.T) {
+	for _, name := range models.KeyNotationNames() {
+		if notation, err := models.ParseKeyNotation(name); err != nil || notation.Name != name {
+			t.Errorf("ParseKeyNotation(%q) = %v, %v", name, notation, err)
+		}
+	}
+	if _, err := models.ParseKeyNotation("solfege"); err == nil {
+		t.Error("ParseKeyNotation(\"solfege\") didn't fail")
+	}
+}
@@ -4,39 +4,40 @@ package models
 var PitchToCamelot = map[string]string{
 	// Major keys (B)
 	"C":  "8B",
-	"C#": "1B",
-	"Db": "1B",
-	"D":  "3B",
-	"D#": "4B",
-	"Eb": "4B",
-	"E":  "5B",
-	"F":  "6B",
-	"F#": "7B",
-	"Gb": "7B",
-	"G":  "8B",
-	"G#": "9B",
-	"Ab": "9B",
-	"A":  "10B",
-	"A#": "11B",
-	"Bb": "11B",
-	"B":  "12B",
+	"C#": "3B",
+	"Db": "3B",
+	"D":  "10B",
+	"D#": "5B",
+	"Eb": "5B",
+	"E":  "12B",
+	"F":  "7B",
+	"F#": "2B",
+	"Gb": "2B",
+	"G":  "9B",
+	"G#": "4B",
+	"Ab": "4B",
+	"A":  "11B",
+	"A#": "6B",
+	"Bb": "6B",
+	"B":  "1B",
+	"Cb": "1B",
 
 	// Minor keys (A)
+	"Cm":  "5A",
+	"C#m": "12A",
+	"Dbm": "12A",
+	"Dm":  "7A",
+	"D#m": "2A",
+	"Ebm": "2A",
+	"Em":  "9A",
+	"Fm":  "4A",
+	"F#m": "11A",
+	"Gbm": "11A",
+	"Gm":  "6A",
+	"G#m": "1A",
+	"Abm": "1A",
 	"Am":  "8A",
-	"A#m": "1A",
-	"Bbm": "1A",
-	"Bm":  "3A",
-	"Cm":  "4A",
-	"C#m": "5A",
-	"Dbm": "5A",
-	"Dm":  "6A",
-	"D#m": "7A",
-	"Ebm": "7A",
-	"Em":  "8A",
-	"Fm":  "9A",
-	"F#m": "10A",
-	"Gbm": "10A",
-	"Gm":  "11A",
-	"G#m": "12A",
-	"Abm": "12A",
+	"A#m": "3A",
+	"Bbm": "3A",
+	"Bm":  "10A",
 }
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"math/bits"
+	"slices"
+
+	"github.com/xdave/keyid/interfaces"
+)
+
+// The semitones of the major scale above its tonic
+var majorSteps = []int{0, 2, 4, 5, 7, 9, 11}
+
+// pitchClasses sets bit n for each note of key, C being 0. Every kind on a
+// Camelot number plays the notes of the major key there, C major being 8B and
+// every step round the wheel a fifth (7 semitones) up.
+func pitchClasses(key interfaces.Scale) uint16 {
+	root := 7 * (key.GetIndex() - 8)
+	var classes uint16
+	for _, step := range majorSteps {
+		classes |= 1 << (interfaces.ModCyclic(root+step, 12) % 12)
+	}
+	return classes
+}
+
+// sharedNotes counts the notes both keys play, 7 for keys on the same Camelot
+// number, one less for each step round the wheel down to 2.
+func sharedNotes(a interfaces.Scale, b interfaces.Scale) int {
+	return bits.OnesCount16(pitchClasses(a) & pitchClasses(b))
+}
+
+// sameNotesMajor is the major key playing the notes of key.
+func sameNotesMajor(key interfaces.Scale) interfaces.Scale {
+	return &CamelotScale{Index: key.GetIndex(), Kind: interfaces.Major}
+}
+
+// tonicPitch is the note key is on, C being 0.
+func tonicPitch(key interfaces.Scale) int {
+	return interfaces.ModCyclic(7*(key.GetIndex()-8)+key.GetKind().Degree(), 12) % 12
+}
+
+// notePitch finds a note spelled with a sharp or a flat, e.g. "F#" or "Gb",
+// C being 0. Spellings no key signature uses, like "E#" or "Fb", are no
+// notes.
+func notePitch(note string) (int, bool) {
+	if pitch := slices.Index(sharpNotes, note); pitch >= 0 {
+		return pitch, true
+	}
+	pitch := slices.Index(flatNotes, note)
+	return pitch, pitch >= 0
+}
+
+// keyOnTonic returns the key of kind on the note pitch, on the Camelot number
+// of the major key with the same notes.
+func keyOnTonic(pitch int, kind interfaces.ScaleKind) *CamelotScale {
+	// 7 fifths up is a semitone up, so the wheel moves 7 steps a semitone
+	return &CamelotScale{Index: interfaces.ModCyclic(8+7*(pitch-kind.Degree()), 12), Kind: kind}
+}
@@ -0,0 +1,267 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/xdave/keyid/interfaces"
+)
+
+// KeyMove is one way of getting from the key playing to the next one.
+type KeyMove struct {
+	// ID is what custom rule sets list the move by
+	ID   string
+	Name string
+	// Weight ranks the move from 1 (same key) down, how smoothly it mixes
+	Weight float64
+	Move   func(key interfaces.Scale) interfaces.Scale
+}
+
+var (
+	MoveSameKey = &KeyMove{ID: "same", Name: "same key", Weight: 1, Move: func(key interfaces.Scale) interfaces.Scale {
+		return key
+	}}
+	MoveUp = &KeyMove{ID: "+1", Name: "+1", Weight: 0.9, Move: func(key interfaces.Scale) interfaces.Scale {
+		return key.Horizontal(1)
+	}}
+	MoveDown = &KeyMove{ID: "-1", Name: "-1", Weight: 0.9, Move: func(key interfaces.Scale) interfaces.Scale {
+		return key.Horizontal(-1)
+	}}
+	MoveRelative = &KeyMove{ID: "relative", Name: "relative major/minor", Weight: 0.95, Move: func(key interfaces.Scale) interfaces.Scale {
+		return key.Vertical()
+	}}
+	MoveDiagonal = &KeyMove{ID: "diagonal", Name: "diagonal", Weight: 0.75, Move: func(key interfaces.Scale) interfaces.Scale {
+		return key.Diagonal()
+	}}
+	MoveParallel = &KeyMove{ID: "parallel", Name: "parallel major/minor", Weight: 0.7, Move: func(key interfaces.Scale) interfaces.Scale {
+		return key.MajorToMinor()
+	}}
+	// -3 and +9 are the same move on a wheel of 12
+	MoveDownThree = &KeyMove{ID: "-3", Name: "-3 (+9)", Weight: 0.65, Move: func(key interfaces.Scale) interfaces.Scale {
+		return key.Horizontal(-3)
+	}}
+	MoveEnergyBoost = &KeyMove{ID: "+2", Name: "energy boost +2", Weight: 0.8, Move: func(key interfaces.Scale) interfaces.Scale {
+		return key.Horizontal(2)
+	}}
+	MoveJaws = &KeyMove{ID: "-5", Name: "jaws -5", Weight: 0.55, Move: func(key interfaces.Scale) interfaces.Scale {
+		return key.Horizontal(-5)
+	}}
+	MoveFlatToMinor = &KeyMove{ID: "flat", Name: "flat to minor", Weight: 0.5, Move: func(key interfaces.Scale) interfaces.Scale {
+		return key.FlatToMinor()
+	}}
+	MoveSemitoneUp = &KeyMove{ID: "+7", Name: "semitone up +7", Weight: 0.6, Move: func(key interfaces.Scale) interfaces.Scale {
+		return key.Horizontal(7)
+	}}
+	MoveEnergyDrop = &KeyMove{ID: "-2", Name: "energy drop -2", Weight: 0.7, Move: func(key interfaces.Scale) interfaces.Scale {
+		return key.Horizontal(-2)
+	}}
+)
+
+// MoveUnknownKey is how tracks without a usable key mix when they are asked
+// for, see ScoreUnknownKey. It ranks them below any harmonic move and is never
+// part of a rule set.
+var MoveUnknownKey = &KeyMove{ID: "unknown", Name: "unknown key", Weight: 0.3, Move: func(key interfaces.Scale) interfaces.Scale {
+	return key
+}}
+
+// sharedNotesMoves are how RuleSet.Match matches modes, by how many notes the
+// keys share. All seven weigh as much as the relative key, which is the same
+// notes too, and each note less a seventh less.
+var sharedNotesMoves = func() []*KeyMove {
+	moves := make([]*KeyMove, 8)
+	for shared := range moves {
+		moves[shared] = &KeyMove{
+			ID:     fmt.Sprintf("notes%d", shared),
+			Name:   fmt.Sprintf("%d shared notes", shared),
+			Weight: MoveRelative.Weight * float64(shared) / 7,
+			Move: func(key interfaces.Scale) interfaces.Scale {
+				return key
+			},
+		}
+	}
+	return moves
+}()
+
+// KeyMoves lists every move a rule set can allow.
+var KeyMoves = []*KeyMove{
+	MoveSameKey, MoveUp, MoveDown, MoveRelative, MoveDiagonal, MoveParallel,
+	MoveDownThree, MoveEnergyBoost, MoveJaws, MoveFlatToMinor, MoveSemitoneUp, MoveEnergyDrop,
+}
+
+// RuleSet is a named list of the key moves that make two tracks compatible.
+type RuleSet struct {
+	Name  string
+	Moves []*KeyMove
+}
+
+var (
+	StrictRules = &RuleSet{Name: "strict", Moves: []*KeyMove{
+		MoveSameKey, MoveRelative,
+	}}
+	ClassicRules = &RuleSet{Name: "classic", Moves: []*KeyMove{
+		MoveSameKey, MoveUp, MoveDown, MoveRelative,
+	}}
+	KeyidRules = &RuleSet{Name: "keyid", Moves: []*KeyMove{
+		MoveSameKey, MoveParallel, MoveUp, MoveDown, MoveDiagonal, MoveRelative,
+		MoveDownThree, MoveEnergyBoost, MoveJaws, MoveFlatToMinor,
+	}}
+	AdventurousRules = &RuleSet{Name: "adventurous", Moves: []*KeyMove{
+		MoveSameKey, MoveParallel, MoveUp, MoveDown, MoveDiagonal, MoveRelative,
+		MoveDownThree, MoveEnergyBoost, MoveJaws, MoveFlatToMinor, MoveSemitoneUp, MoveEnergyDrop,
+	}}
+)
+
+// RuleSets are the presets, from the fewest moves to the most.
+var RuleSets = []*RuleSet{StrictRules, ClassicRules, KeyidRules, AdventurousRules}
+
+// RuleSetNames returns the names of the presets.
+func RuleSetNames() []string {
+	names := []string{}
+	for _, rules := range RuleSets {
+		names = append(names, rules.Name)
+	}
+	return names
+}
+
+// ParseRuleSet returns the preset with the given name, or a custom rule set
+// from a comma separated list of move IDs like "same,+1,-1,relative".
+func ParseRuleSet(name string) (*RuleSet, error) {
+	for _, rules := range RuleSets {
+		if rules.Name == name {
+			return rules, nil
+		}
+	}
+
+	custom := &RuleSet{Name: name}
+	for _, id := range strings.Split(name, ",") {
+		move := findKeyMove(strings.TrimSpace(id))
+		if move == nil {
+			return nil, fmt.Errorf("unknown rule set or key move '%s' (rule sets: %s, moves: %s)",
+				id, strings.Join(RuleSetNames(), ", "), strings.Join(keyMoveIDs(), ", "))
+		}
+		custom.Moves = append(custom.Moves, move)
+	}
+	return custom, nil
+}
+
+func findKeyMove(id string) *KeyMove {
+	for _, move := range KeyMoves {
+		if move.ID == id {
+			return move
+		}
+	}
+	return nil
+}
+
+func keyMoveIDs() []string {
+	ids := []string{}
+	for _, move := range KeyMoves {
+		ids = append(ids, move.ID)
+	}
+	return ids
+}
+
+// Match returns the heaviest move that gets from one key to the other, some
+// keys are reached by more than one move. Modes are matched by their notes,
+// see matchNotes.
+func (r *RuleSet) Match(from interfaces.Scale, to interfaces.Scale) (*KeyMove, bool) {
+	if from.GetKind().IsMode() || to.GetKind().IsMode() {
+		return r.matchNotes(from, to)
+	}
+	var best *KeyMove
+	for _, move := range r.Moves {
+		if to.IsEqual(move.Move(from)) && (best == nil || move.Weight > best.Weight) {
+			best = move
+		}
+	}
+	return best, best != nil
+}
+
+// matchNotes matches keys when either is a mode, which the moves round the
+// wheel don't cover: the same key when the rule set allows it, otherwise keys
+// whose notes are those of two major keys the rule set allows, weighted by how
+// many notes they share.
+func (r *RuleSet) matchNotes(from interfaces.Scale, to interfaces.Scale) (*KeyMove, bool) {
+	if !from.IsKnown() || !to.IsKnown() {
+		return nil, false
+	}
+	if from.IsEqual(to) && slices.Contains(r.Moves, MoveSameKey) {
+		return MoveSameKey, true
+	}
+	if !r.Allows(sameNotesMajor(from), sameNotesMajor(to)) {
+		return nil, false
+	}
+	return sharedNotesMoves[sharedNotes(from, to)], true
+}
+
+// Allows tells if any move of the rule set gets from one key to the other.
+func (r *RuleSet) Allows(from interfaces.Scale, to interfaces.Scale) bool {
+	_, ok := r.Match(from, to)
+	return ok
+}
+
+// Score rates mixing from track into next: the weight of the key move, less
+// up to half of it the further apart the tempos are, as far as the pitch range
+// and the tolerance go, and less the detune of the next track, so a quarter
+// tone off halves the score. next is played at the tempo of track first, see
+// AsBpm, or at half or double of it and so on when the tempo options allow,
+// whichever scores best.
+func (r *RuleSet) Score(track interfaces.Item, next interfaces.Item, tempo interfaces.TempoOptions) interfaces.Transition {
+	return bestScore(track, next, tempo, r.Match)
+}
+
+// ScoreUnknownKey rates mixing from track into next when either key is
+// unknown, by tempo alone, as MoveUnknownKey.
+func ScoreUnknownKey(track interfaces.Item, next interfaces.Item, tempo interfaces.TempoOptions) interfaces.Transition {
+	return bestScore(track, next, tempo, func(from interfaces.Scale, to interfaces.Scale) (*KeyMove, bool) {
+		return MoveUnknownKey, true
+	})
+}
+
+// bestScore scores next at every multiplier the tempo options allow, keeping
+// the best one.
+func bestScore(track interfaces.Item, next interfaces.Item, tempo interfaces.TempoOptions, match func(from interfaces.Scale, to interfaces.Scale) (*KeyMove, bool)) interfaces.Transition {
+	best := interfaces.Transition{}
+	for _, multiplier := range tempo.Multipliers() {
+		if transition := scoreAt(track, next, tempo, multiplier, match); transition.Score > best.Score {
+			best = transition
+		}
+	}
+	return best
+}
+
+// scoreAt scores next played so that its BPM times multiplier is the BPM of
+// track.
+func scoreAt(track interfaces.Item, next interfaces.Item, tempo interfaces.TempoOptions, multiplier float64, match func(from interfaces.Scale, to interfaces.Scale) (*KeyMove, bool)) interfaces.Transition {
+	pitched := next.AsBpm(track.GetBPM()/multiplier, tempo)
+	if !track.BpmMatchesTarget(pitched.GetBPM()*multiplier, tempo) {
+		return interfaces.Transition{}
+	}
+	move, ok := match(track.GetScale(), pitched.GetScale())
+	if !ok {
+		return interfaces.Transition{}
+	}
+
+	transition := interfaces.Transition{
+		Move:       move.Name,
+		BPMDelta:   (next.GetBPM()*multiplier - track.GetBPM()) / track.GetBPM() * 100,
+		Multiplier: multiplier,
+	}
+	if !tempo.KeyLock {
+		shift := interfaces.PitchShift(next.GetBPM(), pitched.GetBPM())
+		transition.Pitch = int(math.Round(shift))
+		transition.Detune = shift - math.Round(shift)
+	}
+	transition.Score = move.Weight *
+		(1 - 0.5*math.Min(math.Abs(transition.BPMDelta)/maxBPMDelta(tempo), 1)) *
+		(1 - math.Abs(transition.Detune))
+	return transition
+}
+
+// maxBPMDelta is the furthest, in percent, a track can be from another's BPM
+// and still mix.
+func maxBPMDelta(tempo interfaces.TempoOptions) float64 {
+	return math.Max(tempo.PitchRange+tempo.Tolerance, 0.1)
+}
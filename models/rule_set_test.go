@@ -0,0 +1,358 @@
+package models_test
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/xdave/keyid/client"
+	"github.com/xdave/keyid/interfaces"
+	"github.com/xdave/keyid/models"
+)
+
+func keyTrack(key string, bpm float64) interfaces.Item {
+	return &client.Track{ID: key, BPM: bpm, Scale: models.NewKey(key), Title: key, Tags: []string{}}
+}
+
+func TestParseRuleSet(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    []string
+		wantErr string
+	}{
+		{"strict", []string{"same", "relative"}, ""},
+		{"classic", []string{"same", "+1", "-1", "relative"}, ""},
+		{"keyid", []string{"same", "parallel", "+1", "-1", "diagonal", "relative", "-3", "+2", "-5", "flat"}, ""},
+		{"adventurous", []string{"same", "parallel", "+1", "-1", "diagonal", "relative", "-3", "+2", "-5", "flat", "+7", "-2"}, ""},
+		{"same, +1,relative", []string{"same", "+1", "relative"}, ""},
+		{"same,+3", nil, "unknown rule set or key move '+3'"},
+		{"loose", nil, "unknown rule set or key move 'loose'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := models.ParseRuleSet(tt.name)
+			if tt.wantErr != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseRuleSet() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids := []string{}
+			for _, move := range rules.Moves {
+				ids = append(ids, move.ID)
+			}
+			if strings.Join(ids, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("moves = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
+
+// The move each preset matches from 8A (A minor) to a key, "" for none.
+func TestRuleSetMatch(t *testing.T) {
+	tests := []struct {
+		to                                  string
+		strict, classic, keyid, adventurous string
+	}{
+		{"8A", "same key", "same key", "same key", "same key"},
+		{"8B", "relative major/minor", "relative major/minor", "relative major/minor", "relative major/minor"},
+		{"9A", "", "+1", "+1", "+1"},
+		{"7A", "", "-1", "-1", "-1"},
+		{"7B", "", "", "diagonal", "diagonal"},
+		{"11B", "", "", "parallel major/minor", "parallel major/minor"},
+		{"5A", "", "", "-3 (+9)", "-3 (+9)"},
+		{"10A", "", "", "energy boost +2", "energy boost +2"},
+		{"4B", "", "", "flat to minor", "flat to minor"},
+		// jaws -5 and semitone up +7 both get there, the heavier one wins
+		{"3A", "", "", "jaws -5", "semitone up +7"},
+		{"6A", "", "", "", "energy drop -2"},
+		{"2A", "", "", "", ""},
+		{"9B", "", "", "", ""},
+	}
+	for _, tt := range tests {
+		for i, rules := range models.RuleSets {
+			want := []string{tt.strict, tt.classic, tt.keyid, tt.adventurous}[i]
+			move, ok := rules.Match(models.NewKey("8A"), models.NewKey(tt.to))
+			got := ""
+			if ok {
+				got = move.Name
+			}
+			if got != want {
+				t.Errorf("%s: Match(8A, %s) = %q, want %q", rules.Name, tt.to, got, want)
+			}
+			if rules.Allows(models.NewKey("8A"), models.NewKey(tt.to)) != ok {
+				t.Errorf("%s: Allows(8A, %s) disagrees with Match", rules.Name, tt.to)
+			}
+		}
+	}
+}
+
+// Moves are the same round the wheel, from 12 to 1 too.
+func TestRuleSetMatchWraps(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     string
+	}{
+		{"12A", "1A", "+1"},
+		{"1B", "12B", "-1"},
+		{"11A", "1A", "energy boost +2"},
+		{"1A", "12B", "diagonal"},
+		{"2A", "9A", "jaws -5"},
+	}
+	for _, tt := range tests {
+		move, ok := models.KeyidRules.Match(models.NewKey(tt.from), models.NewKey(tt.to))
+		if !ok || move.Name != tt.want {
+			t.Errorf("Match(%s, %s) = %v, want %q", tt.from, tt.to, move, tt.want)
+		}
+	}
+}
+
+// With key lock the score is the weight of the move, less up to half of it
+// for the tempo change as far as the pitch range and the tolerance go.
+func TestRuleSetScore(t *testing.T) {
+	keyLock := interfaces.TempoOptions{PitchRange: 6, Tolerance: 1.8, KeyLock: true}
+	tests := []struct {
+		name      string
+		next      interfaces.Item
+		tempo     interfaces.TempoOptions
+		wantScore float64
+		wantMove  string
+		wantDelta float64
+	}{
+		{"same key and tempo", keyTrack("8A", 120), keyLock, 1, "same key", 0},
+		{"relative", keyTrack("8B", 120), keyLock, 0.95, "relative major/minor", 0},
+		{"+1", keyTrack("9A", 120), keyLock, 0.9, "+1", 0},
+		{"5% faster", keyTrack("8B", 126), keyLock, 0.95 * (1 - 0.5*5/7.8), "relative major/minor", 5},
+		{"3% slower", keyTrack("8A", 116.4), keyLock, 1 - 0.5*3/7.8, "same key", -3},
+		{"within the tolerance only", keyTrack("8A", 122), interfaces.TempoOptions{Tolerance: 1.8}, 1 - 0.5*(2.0/120*100)/1.8, "same key", 2.0 / 120 * 100},
+		{"past the tolerance", keyTrack("8A", 123), interfaces.TempoOptions{Tolerance: 1.8}, 0, "", 0},
+		{"past the pitch range", keyTrack("8A", 140), keyLock, 0, "", 0},
+		{"no move", keyTrack("2A", 120), keyLock, 0, "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transition := models.KeyidRules.Score(keyTrack("8A", 120), tt.next, tt.tempo)
+			if math.Abs(transition.Score-tt.wantScore) > 1e-9 {
+				t.Errorf("Score = %v, want %v", transition.Score, tt.wantScore)
+			}
+			if transition.IsCompatible() != (tt.wantScore > 0) {
+				t.Errorf("IsCompatible() = %v", transition.IsCompatible())
+			}
+			if transition.Move != tt.wantMove || math.Abs(transition.BPMDelta-tt.wantDelta) > 1e-9 {
+				t.Errorf("Move, BPMDelta = %q, %v, want %q, %v", transition.Move, transition.BPMDelta, tt.wantMove, tt.wantDelta)
+			}
+		})
+	}
+}
+
+func TestTransitionString(t *testing.T) {
+	tests := []struct {
+		transition interfaces.Transition
+		want       string
+	}{
+		{interfaces.Transition{}, "no compatible move"},
+		{interfaces.Transition{Score: 0.9, Move: "+1", BPMDelta: 2.04}, "+1, +2.0% BPM"},
+		{interfaces.Transition{Score: 0.5, Move: "same key", BPMDelta: -0.04}, "same key, +0.0% BPM"},
+		{interfaces.Transition{Score: 0.5, Move: "energy boost +2", BPMDelta: -5.2, Pitch: 1, Detune: -0.08}, "energy boost +2, pitched +1 semitone, detuned -0.08, -5.2% BPM"},
+		{interfaces.Transition{Score: 0.5, Move: "relative major/minor", Pitch: -2, Multiplier: 2}, "relative major/minor, half-time, pitched -2 semitones, +0.0% BPM"},
+	}
+	for _, tt := range tests {
+		if got := tt.transition.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestPitchShift(t *testing.T) {
+	tests := []struct {
+		bpm, target float64
+		want        float64
+	}{
+		{120, 120, 0},
+		{100, 200, 12},
+		{100, 50, -12},
+		{100, 105, 0.8447},
+		{100, 94, -1.0712},
+	}
+	for _, tt := range tests {
+		if got := interfaces.PitchShift(tt.bpm, tt.target); math.Abs(got-tt.want) > 1e-4 {
+			t.Errorf("PitchShift(%v, %v) = %v, want %v", tt.bpm, tt.target, got, tt.want)
+		}
+	}
+}
+
+// Without key lock the key moves by the pitch shift rounded to the nearest
+// semitone, 7 steps round the wheel each.
+func TestAsBpm(t *testing.T) {
+	pitch6 := interfaces.TempoOptions{PitchRange: 6, Tolerance: 1.8}
+	tests := []struct {
+		name    string
+		target  float64
+		tempo   interfaces.TempoOptions
+		wantBPM float64
+		wantKey string
+	}{
+		{"same tempo", 100, pitch6, 100, "8A"},
+		{"5% up is a semitone up", 105, pitch6, 105, "3A"},
+		{"2% down stays in key", 98, pitch6, 98, "8A"},
+		{"3% down is a semitone down", 97, pitch6, 97, "1A"},
+		{"clamped to the pitch range", 110, pitch6, 106, "3A"},
+		{"key lock", 105, interfaces.TempoOptions{PitchRange: 6, KeyLock: true}, 105, "8A"},
+		{"no pitch", 105, interfaces.TempoOptions{}, 100, "8A"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pitched := keyTrack("8A", 100).AsBpm(tt.target, tt.tempo)
+			if math.Abs(pitched.GetBPM()-tt.wantBPM) > 1e-9 || !pitched.GetScale().IsEqual(models.NewKey(tt.wantKey)) {
+				t.Errorf("AsBpm(%v) = %v %v, want %v %v", tt.target, pitched.GetBPM(), pitched.GetScale(), tt.wantBPM, tt.wantKey)
+			}
+		})
+	}
+}
+
+// A track pitched to the tempo playing is matched in the key it ends up in,
+// and loses as much of its score as it is detuned from it.
+func TestRuleSetScorePitchShift(t *testing.T) {
+	pitch6 := interfaces.TempoOptions{PitchRange: 6, Tolerance: 1.8}
+	// 5% slower, played at 120 it is 0.84 semitones up
+	slower := 120 / 1.05
+	shift := interfaces.PitchShift(slower, 120)
+	tempoFactor := 1 - 0.5*math.Abs((slower-120)/120*100)/7.8
+	tests := []struct {
+		name       string
+		next       interfaces.Item
+		tempo      interfaces.TempoOptions
+		wantMove   string
+		wantPitch  int
+		wantDetune float64
+		wantScore  float64
+	}{
+		{"a semitone below lands on the same key", keyTrack("1A", slower), pitch6, "same key", 1, shift - 1, tempoFactor * (1 - math.Abs(shift-1))},
+		{"the same key lands a semitone up", keyTrack("8A", slower), pitch6, "jaws -5", 1, shift - 1, 0.55 * tempoFactor * (1 - math.Abs(shift-1))},
+		{"key lock keeps the key", keyTrack("8A", slower), interfaces.TempoOptions{PitchRange: 6, Tolerance: 1.8, KeyLock: true}, "same key", 0, 0, tempoFactor},
+		{"a detune below half a semitone stays in key", keyTrack("8A", 120/1.02), pitch6, "same key", 0, interfaces.PitchShift(120/1.02, 120),
+			(1 - 0.5*(1-1/1.02)*100/7.8) * (1 - interfaces.PitchShift(120/1.02, 120))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transition := models.KeyidRules.Score(keyTrack("8A", 120), tt.next, tt.tempo)
+			if transition.Move != tt.wantMove || transition.Pitch != tt.wantPitch {
+				t.Errorf("Move, Pitch = %q, %d, want %q, %d", transition.Move, transition.Pitch, tt.wantMove, tt.wantPitch)
+			}
+			if math.Abs(transition.Detune-tt.wantDetune) > 1e-9 || math.Abs(transition.Score-tt.wantScore) > 1e-9 {
+				t.Errorf("Detune, Score = %v, %v, want %v, %v", transition.Detune, transition.Score, tt.wantDetune, tt.wantScore)
+			}
+		})
+	}
+}
+
+func TestTempoMultipliers(t *testing.T) {
+	tests := []struct {
+		tempo interfaces.TempoOptions
+		want  []float64
+	}{
+		{interfaces.TempoOptions{}, []float64{1}},
+		{interfaces.TempoOptions{HalfTime: true}, []float64{1, 2, 0.5}},
+		{interfaces.TempoOptions{ThreeFour: true}, []float64{1, 1.5, 2.0 / 3}},
+		{interfaces.TempoOptions{HalfTime: true, ThreeFour: true}, []float64{1, 2, 0.5, 1.5, 2.0 / 3}},
+	}
+	for _, tt := range tests {
+		if got := tt.tempo.Multipliers(); !slices.Equal(got, tt.want) {
+			t.Errorf("Multipliers() = %v, want %v", got, tt.want)
+		}
+	}
+}
+
+// Tracks at half, double or 3/4 of the tempo only mix when the tempo options
+// allow it, and say so.
+func TestRuleSetScoreTempoFamilies(t *testing.T) {
+	tests := []struct {
+		name     string
+		bpm      float64
+		next     float64
+		halfTime bool
+		three    bool
+		wantName string
+	}{
+		{"half-time off", 170, 85, false, false, ""},
+		{"half-time", 170, 85, true, false, "same key, half-time"},
+		{"dubstep half-time", 140, 70, true, false, "same key, half-time"},
+		{"double-time", 85, 170, true, false, "same key, double-time"},
+		{"3/4 off", 120, 80, true, false, ""},
+		{"3/4 feel", 120, 80, false, true, "same key, 3/4 feel"},
+		{"3/4 feel the other way", 80, 120, false, true, "same key, 3/4 feel"},
+		{"same tempo still first", 120, 120, true, true, "same key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempo := interfaces.TempoOptions{PitchRange: 6, Tolerance: 1.8, HalfTime: tt.halfTime, ThreeFour: tt.three}
+			transition := models.KeyidRules.Score(keyTrack("8A", tt.bpm), keyTrack("8A", tt.next), tempo)
+			if tt.wantName == "" {
+				if transition.IsCompatible() {
+					t.Errorf("Score() = %v, want no compatible move", transition)
+				}
+				return
+			}
+			if transition.Name() != tt.wantName || transition.Score != 1 || transition.Pitch != 0 {
+				t.Errorf("Score() = %v (score %v), want %q scoring 1", transition, transition.Score, tt.wantName)
+			}
+		})
+	}
+}
+
+// Modes match by the notes they share, when the major keys with the same
+// notes are allowed.
+func TestRuleSetMatchModes(t *testing.T) {
+	tests := []struct {
+		rules    *models.RuleSet
+		from, to string
+		wantID   string
+		wantName string
+	}{
+		{models.KeyidRules, "D dorian", "D dorian", "same", "same key"},
+		{models.KeyidRules, "D dorian", "Am", "notes7", "7 shared notes"},
+		{models.KeyidRules, "Am", "D dorian", "notes7", "7 shared notes"},
+		{models.KeyidRules, "D dorian", "C", "notes7", "7 shared notes"},
+		{models.KeyidRules, "D dorian", "G mixolydian", "notes7", "7 shared notes"},
+		{models.KeyidRules, "D dorian", "9B", "notes6", "6 shared notes"},
+		{models.KeyidRules, "D dorian", "E dorian", "notes5", "5 shared notes"},
+		{models.KeyidRules, "D dorian", "2A", "", ""},
+		{models.KeyidRules, "D dorian", "", "", ""},
+		{models.StrictRules, "D dorian", "9B", "", ""},
+		{models.StrictRules, "D dorian", "A aeolian", "notes7", "7 shared notes"},
+		{&models.RuleSet{Name: "relative", Moves: []*models.KeyMove{models.MoveRelative}}, "D dorian", "D dorian", "", ""},
+	}
+	for _, tt := range tests {
+		move, ok := tt.rules.Match(models.NewKey(tt.from), models.NewKey(tt.to))
+		if tt.wantID == "" {
+			if ok {
+				t.Errorf("%s: Match(%s, %s) = %q, want none", tt.rules.Name, tt.from, tt.to, move.Name)
+			}
+			continue
+		}
+		if !ok || move.ID != tt.wantID || move.Name != tt.wantName {
+			t.Errorf("%s: Match(%s, %s) = %v, want %s %q", tt.rules.Name, tt.from, tt.to, move, tt.wantID, tt.wantName)
+		}
+	}
+}
+
+// Each number of shared notes is its own move, weighed by how many there are.
+func TestRuleSetScoreModes(t *testing.T) {
+	tempo := interfaces.TempoOptions{PitchRange: 6, Tolerance: 1.8, KeyLock: true}
+	seen := map[string]float64{}
+	for _, key := range []string{"Am", "9B", "E dorian", "F dorian"} {
+		transition := models.AdventurousRules.Score(keyTrack("D dorian", 120), keyTrack(key, 120), tempo)
+		move, _ := models.AdventurousRules.Match(models.NewKey("D dorian"), models.NewKey(key))
+		if want := 0.95 * float64(7-len(seen)) / 7; math.Abs(transition.Score-want) > 1e-9 {
+			t.Errorf("D dorian to %s scores %v, want %v", key, transition.Score, want)
+		}
+		if _, ok := seen[move.ID]; ok {
+			t.Errorf("D dorian to %s is move %s again", key, move.ID)
+		}
+		seen[move.ID] = transition.Score
+	}
+}
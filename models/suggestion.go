@@ -0,0 +1,26 @@
+package models
+
+import "github.com/xdave/keyid/interfaces"
+
+// Suggestion wraps a track with the transition that made it compatible, so
+// the CLI and the GUI can tell why it was picked.
+type Suggestion struct {
+	interfaces.Item
+	Transition interfaces.Transition
+}
+
+// NewSuggestion explains item, replacing the transition of an item that is
+// already a suggestion.
+func NewSuggestion(item interfaces.Item, transition interfaces.Transition) *Suggestion {
+	if suggestion, ok := item.(*Suggestion); ok {
+		item = suggestion.Item
+	}
+	return &Suggestion{
+		Item:       item,
+		Transition: transition,
+	}
+}
+
+func (s *Suggestion) GetTransition() interfaces.Transition {
+	return s.Transition
+}
@@ -0,0 +1,143 @@
+package models
+
+import (
+	"sync"
+
+	"github.com/xdave/keyid/interfaces"
+)
+
+// SyncCollection is an IndexedCollection that can be shared between
+// goroutines, like the GUI's now playing, suggest and generate. Items and
+// ForEach work on a snapshot, so callers never see the collection change
+// under them, and the collections Map, Filter and SortWith return are new and
+// not shared.
+type SyncCollection struct {
+	mu    sync.Mutex
+	items *IndexedCollection
+}
+
+func NewSyncCollection(items ...interfaces.Item) *SyncCollection {
+	return &SyncCollection{
+		items: NewIndexedCollection(items...),
+	}
+}
+
+func (c *SyncCollection) snapshot() []interfaces.Item {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]interfaces.Item{}, c.items.Items()...)
+}
+
+func (c *SyncCollection) Contains(item interfaces.Item) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.items.Contains(item)
+}
+
+func (c *SyncCollection) Add(item interfaces.Item) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.items.Add(item)
+}
+
+func (c *SyncCollection) Remove(toRemove interfaces.Item) interfaces.Item {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.items.Remove(toRemove)
+}
+
+func (c *SyncCollection) MoveTo(item interfaces.Item, collection interfaces.Collection) {
+	collection.Add(item)
+	c.Remove(item)
+}
+
+func (c *SyncCollection) Candidates(queries ...interfaces.CandidateQuery) []interfaces.Item {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// The first call builds the index, so this is never just a read
+	return c.items.Candidates(queries...)
+}
+
+func (c *SyncCollection) ForEach(fn func(i interfaces.Item)) {
+	for _, item := range c.snapshot() {
+		fn(item)
+	}
+}
+
+func (c *SyncCollection) Map(mapper func(i interfaces.Item) interfaces.Item) interfaces.Collection {
+	return NewIndexedCollection(c.snapshot()...).Map(mapper)
+}
+
+func (c *SyncCollection) Filter(filter func(i interfaces.Item) bool) interfaces.Collection {
+	return NewIndexedCollection(c.snapshot()...).Filter(filter)
+}
+
+func (c *SyncCollection) Reduce(reducer func(i interfaces.Item, acc interfaces.Item) interfaces.Item) interfaces.Item {
+	return NewIndexedCollection(c.snapshot()...).Reduce(reducer)
+}
+
+func (c *SyncCollection) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.items.Len()
+}
+
+func (c *SyncCollection) IsEmpty() bool {
+	return c.Len() == 0
+}
+
+func (c *SyncCollection) First() interfaces.Item {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.items.First()
+}
+
+func (c *SyncCollection) Last() interfaces.Item {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.items.Last()
+}
+
+func (c *SyncCollection) Get(index int) interfaces.Item {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.items.Get(index)
+}
+
+func (c *SyncCollection) IndexOf(item interfaces.Item) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.items.IndexOf(item)
+}
+
+func (c *SyncCollection) Find(predicate func(i interfaces.Item) bool) interfaces.Item {
+	for _, item := range c.snapshot() {
+		if predicate(item) {
+			return item
+		}
+	}
+	return nil
+}
+
+func (c *SyncCollection) FindIndex(predicate func(i interfaces.Item) bool) int {
+	for i, v := range c.snapshot() {
+		if predicate(v) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (c *SyncCollection) SortWith(comparator func(i, j interfaces.Item) bool) interfaces.Collection {
+	return NewIndexedCollection(c.snapshot()...).SortWith(comparator)
+}
+
+func (c *SyncCollection) Items() []interfaces.Item {
+	return c.snapshot()
+}
+
+func (c *SyncCollection) RandomShuffle() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.items.RandomShuffle()
+}
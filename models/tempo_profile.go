@@ -0,0 +1,64 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/xdave/keyid/interfaces"
+)
+
+// TempoProfile is the range of a pitch fader, the furthest a track can be
+// sped up or slowed down.
+type TempoProfile struct {
+	Name string
+	// PitchRange is in percent either way
+	PitchRange float64
+}
+
+var (
+	NoPitch = &TempoProfile{Name: "no-pitch", PitchRange: 0}
+	Pitch6  = &TempoProfile{Name: "6%", PitchRange: 6}
+	Pitch10 = &TempoProfile{Name: "10%", PitchRange: 10}
+	Pitch16 = &TempoProfile{Name: "16%", PitchRange: 16}
+)
+
+// TempoProfiles are the presets, from the narrowest pitch range to the widest.
+var TempoProfiles = []*TempoProfile{NoPitch, Pitch6, Pitch10, Pitch16}
+
+// The BPM tolerance, in percent, when none is given.
+const DefaultTempoTolerance = 1.8
+
+// The widest tolerance, past which tracks would mix at any tempo.
+const maxTempoTolerance = 10.0
+
+// TempoProfileNames returns the names of the presets.
+func TempoProfileNames() []string {
+	names := []string{}
+	for _, profile := range TempoProfiles {
+		names = append(names, profile.Name)
+	}
+	return names
+}
+
+// ParseTempoProfile returns the preset with the given name.
+func ParseTempoProfile(name string) (*TempoProfile, error) {
+	for _, profile := range TempoProfiles {
+		if profile.Name == name {
+			return profile, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown tempo profile '%s' (tempo profiles: %s)", name, strings.Join(TempoProfileNames(), ", "))
+}
+
+// Options returns the tempo options of the profile with the given BPM
+// tolerance, in percent.
+func (p *TempoProfile) Options(tolerance float64) (interfaces.TempoOptions, error) {
+	if !(tolerance >= 0 && tolerance <= maxTempoTolerance) {
+		return interfaces.TempoOptions{}, fmt.Errorf("tempo tolerance must be between 0 and %g%%, not %g", maxTempoTolerance, tolerance)
+	}
+	return interfaces.TempoOptions{
+		Profile:    p.Name,
+		PitchRange: p.PitchRange,
+		Tolerance:  tolerance,
+	}, nil
+}
@@ -0,0 +1,32 @@
+package models
+
+// TraktorKeyToCamelot maps Traktor's MUSICAL_KEY values to Camelot notation
+var TraktorKeyToCamelot = map[int]string{
+	// Major keys (B)
+	0:  "8B",  // C
+	1:  "3B",  // C#/Db
+	2:  "10B", // D
+	3:  "5B",  // D#/Eb
+	4:  "12B", // E
+	5:  "7B",  // F
+	6:  "2B",  // F#/Gb
+	7:  "9B",  // G
+	8:  "4B",  // G#/Ab
+	9:  "11B", // A
+	10: "6B",  // A#/Bb
+	11: "1B",  // B
+
+	// Minor keys (A)
+	12: "5A",  // Cm
+	13: "12A", // C#m/Dbm
+	14: "7A",  // Dm
+	15: "2A",  // D#m/Ebm
+	16: "9A",  // Em
+	17: "4A",  // Fm
+	18: "11A", // F#m/Gbm
+	19: "6A",  // Gm
+	20: "1A",  // G#m/Abm
+	21: "8A",  // Am
+	22: "3A",  // A#m/Bbm
+	23: "10A", // Bm
+}
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"fmt"
+
+	"github.com/xdave/keyid/interfaces"
+)
+
+// UnknownScale is the key of a track that was never analyzed, or whose key
+// ParseKey can't read. It matches no key, itself included, and every move
+// leaves it unknown, so it never takes part in the Camelot math.
+type UnknownScale struct {
+	// Raw is the key as the source wrote it, empty when it had none
+	Raw string
+}
+
+func (key *UnknownScale) GetIndex() int {
+	return 0
+}
+
+func (key *UnknownScale) GetKind() interfaces.ScaleKind {
+	return interfaces.Major
+}
+
+func (key *UnknownScale) IsKnown() bool {
+	return false
+}
+
+// Reason tells why the key is unknown, for the key report.
+func (key *UnknownScale) Reason() string {
+	if key.Raw == "" {
+		return "no key"
+	}
+	return fmt.Sprintf("unreadable key '%s'", key.Raw)
+}
+
+func (key *UnknownScale) ChangeIndex(amount int) interfaces.Scale {
+	return key
+}
+
+func (key *UnknownScale) SwapKind() interfaces.Scale {
+	return key
+}
+
+func (key *UnknownScale) String() string {
+	return "?"
+}
+
+func (key *UnknownScale) Vertical() interfaces.Scale {
+	return key
+}
+
+func (key *UnknownScale) Horizontal(direction int) interfaces.Scale {
+	return key
+}
+
+func (key *UnknownScale) Diagonal() interfaces.Scale {
+	return key
+}
+
+func (key *UnknownScale) FlatToMinor() interfaces.Scale {
+	return key
+}
+
+func (key *UnknownScale) MajorToMinor() interfaces.Scale {
+	return key
+}
+
+func (key *UnknownScale) IsEqual(other interfaces.Scale) bool {
+	return false
+}
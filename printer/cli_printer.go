@@ -4,17 +4,36 @@ import (
 	"fmt"
 
 	"github.com/xdave/keyid/interfaces"
+	"github.com/xdave/keyid/models"
 )
 
 type CliPrinter struct {
+	notation *models.KeyNotation
 }
 
-func NewCliPrinter() interfaces.Printer {
-	return &CliPrinter{}
+func NewCliPrinter(notation *models.KeyNotation) interfaces.Printer {
+	return &CliPrinter{
+		notation: notation,
+	}
 }
 
 func (c *CliPrinter) PrintHeader() {}
 
+// Print writes the columns of Track.String with the key in the selected
+// notation, adding why a suggested track was picked, or why its key is
+// unknown, as a last column.
 func (c *CliPrinter) Print(track interfaces.Item) {
-	fmt.Println(track)
+	line := fmt.Sprintf("%d\t%s\t%d\t%s - %s",
+		int64(track.GetBPM()),
+		c.notation.Format(track.GetScale()),
+		track.GetEnergy(),
+		track.GetArtist(),
+		track.GetTitle(),
+	)
+	if suggestion, ok := track.(interfaces.Suggestion); ok {
+		line += fmt.Sprintf("\t(%s)", suggestion.GetTransition())
+	} else if unknown, ok := track.GetScale().(*models.UnknownScale); ok {
+		line += fmt.Sprintf("\t(%s)", unknown.Reason())
+	}
+	fmt.Println(line)
 }
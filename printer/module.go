@@ -4,4 +4,5 @@ import "go.uber.org/fx"
 
 var Module = fx.Module("printer",
 	fx.Provide(ProvidePrinter),
+	fx.Provide(ProvideKeyNotation),
 )
@@ -0,0 +1,10 @@
+package printer
+
+import (
+	"github.com/xdave/keyid/args"
+	"github.com/xdave/keyid/models"
+)
+
+func ProvideKeyNotation(args *args.Args) (*models.KeyNotation, error) {
+	return models.ParseKeyNotation(args.KeyNotation)
+}
@@ -3,11 +3,12 @@ package printer
 import (
 	"github.com/xdave/keyid/args"
 	"github.com/xdave/keyid/interfaces"
+	"github.com/xdave/keyid/models"
 )
 
-func ProvidePrinter(args *args.Args) interfaces.Printer {
+func ProvidePrinter(args *args.Args, notation *models.KeyNotation) interfaces.Printer {
 	if args.Mode == interfaces.ModeGenerate && args.M3U {
 		return NewM3uPrinter()
 	}
-	return NewCliPrinter()
+	return NewCliPrinter(notation)
 }
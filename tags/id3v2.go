@@ -0,0 +1,251 @@
+package tags
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+	"unicode/utf16"
+)
+
+var ErrNoID3v2 = errors.New("no ID3v2 tag")
+
+const (
+	id3FlagUnsynchronisation = 0x80
+	id3FlagExtendedHeader    = 0x40
+
+	id3FrameFlagUnsynchronisation = 0x02
+	id3FrameFlagDataLength        = 0x01
+)
+
+// ID3v2.2 uses three character frame IDs
+var id3v22Frames = map[string]string{
+	"TT2": "TIT2",
+	"TP1": "TPE1",
+	"TKE": "TKEY",
+	"TBP": "TBPM",
+	"COM": "COMM",
+}
+
+// readID3v2 reads an ID3v2 tag at the current position of r.
+func readID3v2(r io.Reader, tags *Tags) error {
+	header := make([]byte, 10)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return err
+	}
+	if string(header[:3]) != "ID3" {
+		return ErrNoID3v2
+	}
+
+	version := header[3]
+	flags := header[5]
+	size := syncsafe(header[6:10])
+
+	// Read as much as there is rather than trusting the size up front, a
+	// broken header can claim up to 256MB
+	data, err := io.ReadAll(io.LimitReader(r, int64(size)))
+	if err != nil {
+		return err
+	}
+	if len(data) < int(size) {
+		return io.ErrUnexpectedEOF
+	}
+
+	if version < 4 && flags&id3FlagUnsynchronisation != 0 {
+		data = removeUnsynchronisation(data)
+	}
+
+	if flags&id3FlagExtendedHeader != 0 && len(data) >= 4 {
+		extended := int(binary.BigEndian.Uint32(data[:4]))
+		if version == 4 {
+			extended = int(syncsafe(data[:4]))
+		} else {
+			// The v2.3 size doesn't include the size field itself
+			extended += 4
+		}
+		if extended > len(data) {
+			return nil
+		}
+		data = data[extended:]
+	}
+
+	for len(data) > 0 {
+		var id string
+		var frameSize, headerSize int
+		var frameFlags byte
+
+		if version == 2 {
+			if len(data) < 6 {
+				break
+			}
+			id = id3v22Frames[string(data[:3])]
+			frameSize = int(data[3])<<16 | int(data[4])<<8 | int(data[5])
+			headerSize = 6
+		} else {
+			if len(data) < 10 {
+				break
+			}
+			id = string(data[:4])
+			if version == 4 {
+				frameSize = int(syncsafe(data[4:8]))
+			} else {
+				frameSize = int(binary.BigEndian.Uint32(data[4:8]))
+			}
+			frameFlags = data[9]
+			headerSize = 10
+		}
+
+		if data[0] == 0 || frameSize <= 0 || headerSize+frameSize > len(data) {
+			// Padding or a broken frame
+			break
+		}
+
+		frame := data[headerSize : headerSize+frameSize]
+		data = data[headerSize+frameSize:]
+
+		if version == 4 {
+			if frameFlags&id3FrameFlagDataLength != 0 && len(frame) >= 4 {
+				frame = frame[4:]
+			}
+			if frameFlags&id3FrameFlagUnsynchronisation != 0 {
+				frame = removeUnsynchronisation(frame)
+			}
+		}
+
+		switch id {
+		case "TIT2":
+			tags.Title = id3Text(frame)
+		case "TPE1":
+			tags.Artist = id3Text(frame)
+		case "TKEY":
+			tags.Key = id3Text(frame)
+		case "TBPM":
+			tags.setBPM(id3Text(frame))
+		case "COMM":
+			tags.addComment(id3Comment(frame))
+		}
+	}
+
+	return nil
+}
+
+// readChunkedID3v2 finds the "ID3 " chunk AIFF and WAV files keep their tag in.
+func readChunkedID3v2(r io.ReadSeeker, tags *Tags) error {
+	header := make([]byte, 12)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return err
+	}
+
+	var order binary.ByteOrder = binary.BigEndian
+	if string(header[:4]) == "RIFF" {
+		order = binary.LittleEndian
+	}
+
+	chunk := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(r, chunk); err != nil {
+			return ErrNoID3v2
+		}
+		size := int64(order.Uint32(chunk[4:]))
+		id := strings.ToUpper(string(chunk[:4]))
+		if id == "ID3 " {
+			return readID3v2(io.LimitReader(r, size), tags)
+		}
+		// Chunks are padded to an even size
+		if _, err := r.Seek(size+size%2, io.SeekCurrent); err != nil {
+			return ErrNoID3v2
+		}
+	}
+}
+
+func syncsafe(b []byte) uint32 {
+	return uint32(b[0]&0x7f)<<21 | uint32(b[1]&0x7f)<<14 | uint32(b[2]&0x7f)<<7 | uint32(b[3]&0x7f)
+}
+
+func removeUnsynchronisation(data []byte) []byte {
+	return bytes.ReplaceAll(data, []byte{0xff, 0x00}, []byte{0xff})
+}
+
+// id3Text decodes a text frame, joining multiple values with "/".
+func id3Text(frame []byte) string {
+	if len(frame) == 0 {
+		return ""
+	}
+	values := []string{}
+	for _, value := range id3Split(frame[0], frame[1:]) {
+		values = append(values, id3Decode(frame[0], value))
+	}
+	return strings.Join(values, "/")
+}
+
+// id3Comment decodes a COMM frame: encoding, language, description, text.
+func id3Comment(frame []byte) string {
+	if len(frame) < 4 {
+		return ""
+	}
+	values := id3Split(frame[0], frame[4:])
+	if len(values) < 2 {
+		return ""
+	}
+	return id3Decode(frame[0], bytes.Join(values[1:], id3Terminator(frame[0])))
+}
+
+func id3Terminator(encoding byte) []byte {
+	if encoding == 1 || encoding == 2 {
+		return []byte{0, 0}
+	}
+	return []byte{0}
+}
+
+// id3Split splits null-terminated strings, keeping UTF-16 code units aligned.
+func id3Split(encoding byte, data []byte) [][]byte {
+	terminator := id3Terminator(encoding)
+	values := [][]byte{}
+	for len(data) > 0 {
+		end := -1
+		for i := 0; i+len(terminator) <= len(data); i += len(terminator) {
+			if bytes.Equal(data[i:i+len(terminator)], terminator) {
+				end = i
+				break
+			}
+		}
+		if end < 0 {
+			values = append(values, data)
+			break
+		}
+		values = append(values, data[:end])
+		data = data[end+len(terminator):]
+	}
+	return values
+}
+
+func id3Decode(encoding byte, data []byte) string {
+	switch encoding {
+	case 0:
+		// ISO-8859-1 maps directly onto the first 256 code points
+		runes := make([]rune, len(data))
+		for i, b := range data {
+			runes[i] = rune(b)
+		}
+		return string(runes)
+	case 1, 2:
+		var order binary.ByteOrder = binary.BigEndian
+		if len(data) >= 2 {
+			switch {
+			case data[0] == 0xff && data[1] == 0xfe:
+				order = binary.LittleEndian
+				data = data[2:]
+			case data[0] == 0xfe && data[1] == 0xff:
+				data = data[2:]
+			}
+		}
+		units := make([]uint16, 0, len(data)/2)
+		for i := 0; i+1 < len(data); i += 2 {
+			units = append(units, order.Uint16(data[i:]))
+		}
+		return string(utf16.Decode(units))
+	default:
+		return string(data)
+	}
+}
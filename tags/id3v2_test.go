@@ -0,0 +1,196 @@
+package tags
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+func syncsafeBytes(size int) []byte {
+	return []byte{byte(size >> 21 & 0x7f), byte(size >> 14 & 0x7f), byte(size >> 7 & 0x7f), byte(size & 0x7f)}
+}
+
+// id3v2Tag builds a tag of the given version and flags around its frames.
+func id3v2Tag(version byte, flags byte, frames ...[]byte) []byte {
+	body := bytes.Join(frames, nil)
+	tag := append([]byte{'I', 'D', '3', version, 0, flags}, syncsafeBytes(len(body))...)
+	return append(tag, body...)
+}
+
+// id3Frame builds a frame the way the version spells its header: three
+// character IDs and sizes in v2.2, plain sizes in v2.3 and syncsafe ones in
+// v2.4.
+func id3Frame(version byte, id string, flags byte, body []byte) []byte {
+	var frame []byte
+	switch version {
+	case 2:
+		frame = append([]byte(id), byte(len(body)>>16), byte(len(body)>>8), byte(len(body)))
+	case 3:
+		frame = binary.BigEndian.AppendUint32([]byte(id), uint32(len(body)))
+		frame = append(frame, 0, flags)
+	default:
+		frame = append(append([]byte(id), syncsafeBytes(len(body))...), 0, flags)
+	}
+	return append(frame, body...)
+}
+
+func latin1(text string) []byte {
+	return append([]byte{0}, text...)
+}
+
+// utf16LE spells text in UTF-16 with a little-endian byte order mark.
+func utf16LE(text string) []byte {
+	data := []byte{0xff, 0xfe}
+	for _, unit := range utf16.Encode([]rune(text)) {
+		data = binary.LittleEndian.AppendUint16(data, unit)
+	}
+	return data
+}
+
+func utf16BE(text string) []byte {
+	data := []byte{}
+	for _, unit := range utf16.Encode([]rune(text)) {
+		data = binary.BigEndian.AppendUint16(data, unit)
+	}
+	return data
+}
+
+// comment builds a COMM frame body: encoding, language, description, text.
+func comment(encoding byte, description []byte, text []byte) []byte {
+	body := append([]byte{encoding}, "eng"...)
+	body = append(body, description...)
+	body = append(body, id3Terminator(encoding)...)
+	return append(body, text...)
+}
+
+// mixedInKeyFrames are the frames Mixed In Key writes, in the given version.
+func mixedInKeyFrames(version byte) [][]byte {
+	ids := map[string]string{"TIT2": "TIT2", "TPE1": "TPE1", "TKEY": "TKEY", "TBPM": "TBPM", "COMM": "COMM"}
+	if version == 2 {
+		ids = map[string]string{"TIT2": "TT2", "TPE1": "TP1", "TKEY": "TKE", "TBPM": "TBP", "COMM": "COM"}
+	}
+	return [][]byte{
+		id3Frame(version, ids["TIT2"], 0, latin1("Title")),
+		id3Frame(version, ids["TPE1"], 0, latin1("Artist")),
+		id3Frame(version, ids["TKEY"], 0, latin1("8A")),
+		id3Frame(version, ids["TBPM"], 0, latin1("124")),
+		id3Frame(version, ids["COMM"], 0, comment(0, nil, []byte("8A - Energy 6"))),
+	}
+}
+
+var mixedInKeyTags = Tags{Title: "Title", Artist: "Artist", Key: "8A", BPM: 124, Comment: "8A - Energy 6"}
+
+func TestReadID3v2(t *testing.T) {
+	long := strings.Repeat("x", 300)
+	tests := []struct {
+		name    string
+		tag     []byte
+		want    Tags
+		wantErr error
+	}{
+		{"v2.2", id3v2Tag(2, 0, mixedInKeyFrames(2)...), mixedInKeyTags, nil},
+		{"v2.3", id3v2Tag(3, 0, mixedInKeyFrames(3)...), mixedInKeyTags, nil},
+		{"v2.4", id3v2Tag(4, 0, mixedInKeyFrames(4)...), mixedInKeyTags, nil},
+		{"padding", id3v2Tag(4, 0, append(mixedInKeyFrames(4), make([]byte, 64))...), mixedInKeyTags, nil},
+		{"UTF-16 with byte order marks", id3v2Tag(3, 0,
+			id3Frame(3, "TIT2", 0, append([]byte{1}, utf16LE("Café ♯")...)),
+			id3Frame(3, "TKEY", 0, append([]byte{1}, utf16LE("F#m")...)),
+			id3Frame(3, "COMM", 0, comment(1, utf16LE("desc"), utf16LE("Energy 7 𝄞"))),
+		), Tags{Title: "Café ♯", Key: "F#m", Comment: "Energy 7 𝄞"}, nil},
+		{"UTF-16BE and UTF-8", id3v2Tag(4, 0,
+			id3Frame(4, "TIT2", 0, append([]byte{2}, utf16BE("Naïve")...)),
+			id3Frame(4, "TPE1", 0, append([]byte{3}, "Röyksopp\x00Robyn"...)),
+		), Tags{Title: "Naïve", Artist: "Röyksopp/Robyn"}, nil},
+		{"ISO-8859-1", id3v2Tag(3, 0, id3Frame(3, "TPE1", 0, []byte{0, 'B', 'j', 0xf6, 'r', 'k'})), Tags{Artist: "Björk"}, nil},
+		{"every comment", id3v2Tag(3, 0,
+			id3Frame(3, "COMM", 0, comment(0, []byte("iTunNORM"), []byte(" 0000 "))),
+			id3Frame(3, "COMM", 0, comment(0, nil, []byte("Energy 5"))),
+		), Tags{Comment: "0000\nEnergy 5"}, nil},
+		// 300 bytes is 0x00 0x00 0x02 0x2d syncsafe, 0x00 0x00 0x01 0x2c plain
+		{"syncsafe frame sizes", id3v2Tag(4, 0, id3Frame(4, "TIT2", 0, latin1(long)), id3Frame(4, "TKEY", 0, latin1("1A"))), Tags{Title: long, Key: "1A"}, nil},
+		{"plain v2.3 frame sizes", id3v2Tag(3, 0, id3Frame(3, "TIT2", 0, latin1(long)), id3Frame(3, "TKEY", 0, latin1("1A"))), Tags{Title: long, Key: "1A"}, nil},
+		{"v2.3 extended header", id3v2Tag(3, 0x40, append([]byte{0, 0, 0, 6, 0, 0, 0, 0, 0, 0}, id3Frame(3, "TKEY", 0, latin1("2B"))...)), Tags{Key: "2B"}, nil},
+		{"v2.4 extended header", id3v2Tag(4, 0x40, append([]byte{0, 0, 0, 6, 1, 0}, id3Frame(4, "TKEY", 0, latin1("2B"))...)), Tags{Key: "2B"}, nil},
+		// The frame size is that of the frame once the 0x00 after 0xff is gone
+		{"v2.3 unsynchronisation", id3v2Tag(3, 0x80, []byte("TPE1\x00\x00\x00\x04\x00\x00\x00A\xff\x00B")), Tags{Artist: "AÿB"}, nil},
+		{"v2.4 frame unsynchronisation and data length", id3v2Tag(4, 0,
+			id3Frame(4, "TPE1", 0x03, []byte{0, 0, 0, 4, 0, 'A', 0xff, 0x00, 'B'}),
+		), Tags{Artist: "AÿB"}, nil},
+		{"frame larger than the tag", id3v2Tag(3, 0,
+			id3Frame(3, "TKEY", 0, latin1("3A")),
+			[]byte("TIT2\x00\x00\x10\x00\x00\x00\x00Title"),
+		), Tags{Key: "3A"}, nil},
+		{"frame size past 2GB", id3v2Tag(3, 0,
+			id3Frame(3, "TKEY", 0, latin1("3A")),
+			[]byte("TIT2\xff\xff\xff\xff\x00\x00\x00Title"),
+		), Tags{Key: "3A"}, nil},
+		{"extended header larger than the tag", id3v2Tag(4, 0x40, append([]byte{0, 0, 1, 0}, id3Frame(4, "TKEY", 0, latin1("2B"))...)), Tags{}, nil},
+		{"truncated tag", id3v2Tag(4, 0, mixedInKeyFrames(4)...)[:40], Tags{}, io.ErrUnexpectedEOF},
+		{"tag size past the file", append([]byte("ID3\x04\x00\x00\x7f\x7f\x7f\x7f"), id3Frame(4, "TKEY", 0, latin1("3A"))...), Tags{}, io.ErrUnexpectedEOF},
+		{"truncated header", []byte("ID3\x04\x00"), Tags{}, io.ErrUnexpectedEOF},
+		{"no tag", append([]byte{0xff, 0xfb, 0x90, 0x64}, make([]byte, 32)...), Tags{}, ErrNoID3v2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tags := Tags{}
+			err := readID3v2(bytes.NewReader(tt.tag), &tags)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("readID3v2() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(tags, tt.want) {
+				t.Errorf("readID3v2() = %+v, want %+v", tags, tt.want)
+			}
+		})
+	}
+}
+
+// chunk builds an IFF chunk in the given byte order, padded to an even size.
+func chunk(order binary.AppendByteOrder, id string, data []byte) []byte {
+	c := order.AppendUint32([]byte(id), uint32(len(data)))
+	c = append(c, data...)
+	if len(data)%2 != 0 {
+		c = append(c, 0)
+	}
+	return c
+}
+
+func riff(order binary.AppendByteOrder, magic string, form string, chunks ...[]byte) []byte {
+	body := append([]byte(form), bytes.Join(chunks, nil)...)
+	return append(order.AppendUint32([]byte(magic), uint32(len(body))), body...)
+}
+
+func TestReadChunkedID3v2(t *testing.T) {
+	tag := id3v2Tag(4, 0, mixedInKeyFrames(4)...)
+	be, le := binary.BigEndian, binary.LittleEndian
+	tests := []struct {
+		name    string
+		file    []byte
+		want    Tags
+		wantErr error
+	}{
+		{"AIFF", riff(be, "FORM", "AIFF", chunk(be, "COMM", make([]byte, 18)), chunk(be, "SSND", make([]byte, 64)), chunk(be, "ID3 ", tag)), mixedInKeyTags, nil},
+		{"AIFF odd chunk padded", riff(be, "FORM", "AIFF", chunk(be, "NAME", []byte("odd")), chunk(be, "ID3 ", tag)), mixedInKeyTags, nil},
+		{"WAV", riff(le, "RIFF", "WAVE", chunk(le, "fmt ", make([]byte, 16)), chunk(le, "data", make([]byte, 64)), chunk(le, "id3 ", tag)), mixedInKeyTags, nil},
+		{"WAV without a tag", riff(le, "RIFF", "WAVE", chunk(le, "fmt ", make([]byte, 16)), chunk(le, "data", make([]byte, 64))), Tags{}, ErrNoID3v2},
+		{"chunk size past the file", riff(le, "RIFF", "WAVE", []byte("data\xff\xff\xff\x7f"), chunk(le, "id3 ", tag)), Tags{}, ErrNoID3v2},
+		{"tag chunk truncated", riff(be, "FORM", "AIFF", chunk(be, "ID3 ", tag))[:40], Tags{}, io.ErrUnexpectedEOF},
+		{"truncated header", []byte("RIFF\x00"), Tags{}, io.ErrUnexpectedEOF},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tags := Tags{}
+			err := readChunkedID3v2(bytes.NewReader(tt.file), &tags)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("readChunkedID3v2() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(tags, tt.want) {
+				t.Errorf("readChunkedID3v2() = %+v, want %+v", tags, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package tags
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+var ErrNoMP4Metadata = errors.New("no MP4 metadata")
+
+// Path to the iTunes-style metadata list
+var mp4MetadataPath = []string{"moov", "udta", "meta", "ilst"}
+
+type mp4Atom struct {
+	name string
+	data []byte
+}
+
+// readMP4 walks the atom tree to moov/udta/meta/ilst, seeking past the large
+// media atoms instead of reading them.
+func readMP4(r io.ReadSeeker, tags *Tags) error {
+	end, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	for _, name := range mp4MetadataPath {
+		end, err = seekMP4Atom(r, end, name)
+		if err != nil {
+			return err
+		}
+		if name == "meta" {
+			if err := skipMP4FullAtomHeader(r); err != nil {
+				return err
+			}
+		}
+	}
+
+	start, _ := r.Seek(0, io.SeekCurrent)
+	ilst := make([]byte, end-start)
+	if _, err := io.ReadFull(r, ilst); err != nil {
+		return err
+	}
+
+	for _, item := range parseMP4Atoms(ilst) {
+		var name string
+		var value []byte
+		for _, child := range parseMP4Atoms(item.data) {
+			switch child.name {
+			case "name":
+				// Freeform "----" items carry their name after 4 bytes of flags
+				if len(child.data) > 4 {
+					name = string(child.data[4:])
+				}
+			case "data":
+				// Skip the 4 byte type and 4 byte locale
+				if len(child.data) >= 8 {
+					value = child.data[8:]
+				}
+			}
+		}
+
+		switch item.name {
+		case "\xa9nam":
+			tags.Title = string(value)
+		case "\xa9ART":
+			tags.Artist = string(value)
+		case "\xa9cmt":
+			tags.addComment(string(value))
+		case "tmpo":
+			if len(value) >= 2 {
+				tags.BPM = float64(binary.BigEndian.Uint16(value))
+			}
+		case "----":
+			switch strings.ToLower(name) {
+			case "initialkey", "key":
+				tags.Key = string(value)
+			case "bpm":
+				if bpm, err := strconv.ParseFloat(string(value), 64); err == nil {
+					tags.BPM = bpm
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// seekMP4Atom moves r to the start of the named atom's contents and returns
+// the offset its contents end at.
+func seekMP4Atom(r io.ReadSeeker, end int64, name string) (int64, error) {
+	header := make([]byte, 8)
+	for {
+		position, err := r.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return 0, err
+		}
+		if position+8 > end {
+			return 0, ErrNoMP4Metadata
+		}
+		if _, err := io.ReadFull(r, header); err != nil {
+			return 0, err
+		}
+
+		size := int64(binary.BigEndian.Uint32(header))
+		headerSize := int64(8)
+		switch size {
+		case 0:
+			// The atom runs to the end of its parent
+			size = end - position
+		case 1:
+			large := make([]byte, 8)
+			if _, err := io.ReadFull(r, large); err != nil {
+				return 0, err
+			}
+			size = int64(binary.BigEndian.Uint64(large))
+			headerSize = 16
+		}
+		if size < headerSize || position+size > end {
+			return 0, ErrNoMP4Metadata
+		}
+
+		if string(header[4:]) == name {
+			return position + size, nil
+		}
+		if _, err := r.Seek(position+size, io.SeekStart); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// skipMP4FullAtomHeader skips the version and flags of an ISO "meta" atom.
+// QuickTime-style "meta" atoms don't have them and start with a child atom.
+func skipMP4FullAtomHeader(r io.ReadSeeker) error {
+	versionAndFlags := make([]byte, 4)
+	if _, err := io.ReadFull(r, versionAndFlags); err != nil {
+		return err
+	}
+	if binary.BigEndian.Uint32(versionAndFlags) != 0 {
+		_, err := r.Seek(-4, io.SeekCurrent)
+		return err
+	}
+	return nil
+}
+
+func parseMP4Atoms(data []byte) []mp4Atom {
+	atoms := []mp4Atom{}
+	for len(data) >= 8 {
+		size := int(binary.BigEndian.Uint32(data))
+		if size < 8 || size > len(data) {
+			break
+		}
+		atoms = append(atoms, mp4Atom{name: string(data[4:8]), data: data[8:size]})
+		data = data[size:]
+	}
+	return atoms
+}
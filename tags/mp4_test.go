@@ -0,0 +1,107 @@
+package tags
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+// atom builds an MP4 atom around its contents.
+func atom(name string, contents ...[]byte) []byte {
+	body := bytes.Join(contents, nil)
+	return append(binary.BigEndian.AppendUint32(nil, uint32(8+len(body))), append([]byte(name), body...)...)
+}
+
+// largeAtom builds an atom with a 64 bit size.
+func largeAtom(name string, body []byte) []byte {
+	header := append(binary.BigEndian.AppendUint32(nil, 1), name...)
+	header = binary.BigEndian.AppendUint64(header, uint64(16+len(body)))
+	return append(header, body...)
+}
+
+// dataAtom builds the value of a metadata item, of type 1 for text and 21
+// for integers.
+func dataAtom(dataType byte, value []byte) []byte {
+	return atom("data", []byte{0, 0, 0, dataType, 0, 0, 0, 0}, value)
+}
+
+func textItem(name string, value string) []byte {
+	return atom(name, dataAtom(1, []byte(value)))
+}
+
+// freeformItem builds a "----" item, the way taggers write keys and decimal
+// BPMs MP4 has no item for.
+func freeformItem(name string, value string) []byte {
+	return atom("----",
+		atom("mean", []byte("\x00\x00\x00\x00com.apple.iTunes")),
+		atom("name", append([]byte{0, 0, 0, 0}, name...)),
+		dataAtom(1, []byte(value)),
+	)
+}
+
+func mp4File(meta []byte, media ...[]byte) []byte {
+	file := atom("ftyp", []byte("M4A \x00\x00\x00\x00"))
+	file = append(file, bytes.Join(media, nil)...)
+	return append(file, atom("moov", atom("mvhd", make([]byte, 100)), atom("udta", meta))...)
+}
+
+// isoMeta is a "meta" atom with version and flags, quickTimeMeta one without.
+func isoMeta(items ...[]byte) []byte {
+	return atom("meta", []byte{0, 0, 0, 0}, atom("hdlr", make([]byte, 25)), atom("ilst", items...))
+}
+
+func quickTimeMeta(items ...[]byte) []byte {
+	return atom("meta", atom("hdlr", make([]byte, 25)), atom("ilst", items...))
+}
+
+var mp4Items = [][]byte{
+	textItem("\xa9nam", "Title"),
+	textItem("\xa9ART", "Artist"),
+	textItem("\xa9cmt", "8A - Energy 6"),
+	atom("tmpo", dataAtom(21, []byte{0, 124})),
+	freeformItem("initialkey", "8A"),
+}
+
+func TestReadMP4(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    []byte
+		want    Tags
+		wantErr error
+	}{
+		{"ISO meta", mp4File(isoMeta(mp4Items...)), mixedInKeyTags, nil},
+		{"QuickTime meta", mp4File(quickTimeMeta(mp4Items...)), mixedInKeyTags, nil},
+		{"media before moov", mp4File(isoMeta(mp4Items...), atom("mdat", make([]byte, 4096))), mixedInKeyTags, nil},
+		{"64 bit media size", mp4File(isoMeta(mp4Items...), largeAtom("mdat", make([]byte, 4096))), mixedInKeyTags, nil},
+		{"freeform key and decimal BPM", mp4File(isoMeta(
+			freeformItem("KEY", "F#m"),
+			freeformItem("BPM", "127.5"),
+		)), Tags{Key: "F#m", BPM: 127.5}, nil},
+		{"moov running to the end", append(atom("ftyp", []byte("M4A ")), append([]byte{0, 0, 0, 0}, append([]byte("moov"), atom("udta", isoMeta(mp4Items...))...)...)...), mixedInKeyTags, nil},
+		{"item larger than the list", mp4File(isoMeta(
+			textItem("\xa9nam", "Title"),
+			[]byte("\x00\x00\x10\x00\xa9ART\x00\x00\x00\x10data"),
+		)), Tags{Title: "Title"}, nil},
+		{"data shorter than its header", mp4File(isoMeta(atom("\xa9nam", atom("data", []byte{0, 0, 0, 1})), atom("tmpo", dataAtom(21, []byte{1})))), Tags{}, nil},
+		{"no metadata", mp4File(atom("meta", []byte{0, 0, 0, 0}, atom("hdlr", make([]byte, 25)))), Tags{}, ErrNoMP4Metadata},
+		{"atom larger than the file", append(atom("ftyp", []byte("M4A ")), []byte("\x7f\x00\x00\x00moov")...), Tags{}, ErrNoMP4Metadata},
+		{"atom smaller than its header", append(atom("ftyp", []byte("M4A ")), []byte("\x00\x00\x00\x04moov")...), Tags{}, ErrNoMP4Metadata},
+		{"truncated 64 bit size", append(atom("ftyp", []byte("M4A ")), []byte("\x00\x00\x00\x01mdat\x00\x00")...), Tags{}, io.ErrUnexpectedEOF},
+		{"empty file", nil, Tags{}, ErrNoMP4Metadata},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tags := Tags{}
+			err := readMP4(bytes.NewReader(tt.file), &tags)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("readMP4() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(tags, tt.want) {
+				t.Errorf("readMP4() = %+v, want %+v", tags, tt.want)
+			}
+		})
+	}
+}
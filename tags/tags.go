@@ -0,0 +1,83 @@
+package tags
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Tags holds the embedded metadata keyid needs from an audio file.
+type Tags struct {
+	Title   string
+	Artist  string
+	Key     string
+	BPM     float64
+	Comment string
+}
+
+var ErrUnsupported = errors.New("unsupported file type")
+
+// Extensions lists the file types Read understands.
+var Extensions = []string{".mp3", ".aif", ".aiff", ".wav", ".flac", ".ogg", ".opus", ".m4a", ".mp4"}
+
+func IsSupported(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, supported := range Extensions {
+		if ext == supported {
+			return true
+		}
+	}
+	return false
+}
+
+// Read reads the tags of an audio file, picking the format from its extension.
+func Read(path string) (*Tags, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	tags := &Tags{}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".mp3":
+		err = readID3v2(f, tags)
+	case ".aif", ".aiff", ".wav":
+		err = readChunkedID3v2(f, tags)
+	case ".flac":
+		err = readFlac(f, tags)
+	case ".ogg", ".opus":
+		err = readOgg(f, tags)
+	case ".m4a", ".mp4":
+		err = readMP4(f, tags)
+	default:
+		err = ErrUnsupported
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+func (t *Tags) setBPM(value string) {
+	if bpm, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+		t.BPM = bpm
+	}
+}
+
+// addComment keeps every comment, since Mixed In Key's "Energy N" may not be
+// in the first one.
+func (t *Tags) addComment(comment string) {
+	comment = strings.TrimSpace(comment)
+	if comment == "" {
+		return
+	}
+	if t.Comment != "" {
+		t.Comment += "\n"
+	}
+	t.Comment += comment
+}
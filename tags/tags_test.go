@@ -0,0 +1,59 @@
+package tags
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestIsSupported(t *testing.T) {
+	tests := map[string]bool{
+		"track.mp3": true, "TRACK.MP3": true, "track.aif": true, "track.aiff": true,
+		"track.wav": true, "track.flac": true, "track.ogg": true, "track.opus": true,
+		"track.m4a": true, "track.mp4": true,
+		// Raw ADTS AAC has no atoms to keep tags in
+		"track.aac": false, "track.txt": false, "mp3": false,
+	}
+	for path, want := range tests {
+		if got := IsSupported(path); got != want {
+			t.Errorf("IsSupported(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+// Read picks the format from the extension, whatever its case.
+func TestRead(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string][]byte{
+		"track.mp3":  id3v2Tag(4, 0, mixedInKeyFrames(4)...),
+		"track.AIFF": riff(binary.BigEndian, "FORM", "AIFF", chunk(binary.BigEndian, "ID3 ", id3v2Tag(3, 0, mixedInKeyFrames(3)...))),
+		"track.flac": flacFile(flacBlock(flacVorbisComment, true, vorbisComment(mixedInKeyComments...))),
+		"track.m4a":  mp4File(isoMeta(mp4Items...)),
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		tags, err := Read(path)
+		if err != nil {
+			t.Errorf("Read(%s) error = %v", name, err)
+		} else if !reflect.DeepEqual(*tags, mixedInKeyTags) {
+			t.Errorf("Read(%s) = %+v, want %+v", name, *tags, mixedInKeyTags)
+		}
+	}
+
+	aac := filepath.Join(dir, "track.aac")
+	if err := os.WriteFile(aac, []byte{0xff, 0xf1, 0x50, 0x80}, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Read(aac); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Read(track.aac) error = %v, want %v", err, ErrUnsupported)
+	}
+	if _, err := Read(filepath.Join(dir, "missing.mp3")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Read(missing.mp3) error = %v, want %v", err, os.ErrNotExist)
+	}
+}
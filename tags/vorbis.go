@@ -0,0 +1,145 @@
+package tags
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+)
+
+var ErrNoVorbisComment = errors.New("no Vorbis comment")
+
+const (
+	flacLastBlock     = 0x80
+	flacVorbisComment = 4
+
+	// Enough pages to hold the comment header of any reasonable file
+	oggMaxPages = 64
+)
+
+// readFlac finds the VORBIS_COMMENT metadata block of a FLAC file.
+func readFlac(r io.ReadSeeker, tags *Tags) error {
+	magic := make([]byte, 4)
+	if _, err := io.ReadFull(r, magic); err != nil {
+		return err
+	}
+	if string(magic) != "fLaC" {
+		return ErrNoVorbisComment
+	}
+
+	header := make([]byte, 4)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			return err
+		}
+		blockType := header[0] &^ flacLastBlock
+		size := int64(header[1])<<16 | int64(header[2])<<8 | int64(header[3])
+
+		if blockType == flacVorbisComment {
+			block := make([]byte, size)
+			if _, err := io.ReadFull(r, block); err != nil {
+				return err
+			}
+			return readVorbisComment(block, tags)
+		}
+		if header[0]&flacLastBlock != 0 {
+			return ErrNoVorbisComment
+		}
+		if _, err := r.Seek(size, io.SeekCurrent); err != nil {
+			return err
+		}
+	}
+}
+
+// readOgg reassembles the second packet of an Ogg Vorbis or Opus stream,
+// which holds the comment header.
+func readOgg(r io.Reader, tags *Tags) error {
+	header := make([]byte, 27)
+	packets := [][]byte{}
+	var packet []byte
+
+	for page := 0; page < oggMaxPages && len(packets) < 2; page++ {
+		if _, err := io.ReadFull(r, header); err != nil {
+			return err
+		}
+		if string(header[:4]) != "OggS" {
+			return ErrNoVorbisComment
+		}
+		segments := make([]byte, header[26])
+		if _, err := io.ReadFull(r, segments); err != nil {
+			return err
+		}
+		for _, length := range segments {
+			data := make([]byte, length)
+			if _, err := io.ReadFull(r, data); err != nil {
+				return err
+			}
+			packet = append(packet, data...)
+			// A segment shorter than 255 bytes ends the packet
+			if length < 255 {
+				packets = append(packets, packet)
+				packet = nil
+			}
+		}
+	}
+
+	if len(packets) < 2 {
+		return ErrNoVorbisComment
+	}
+
+	comment := packets[1]
+	switch {
+	case bytes.HasPrefix(comment, []byte("\x03vorbis")):
+		comment = comment[7:]
+	case bytes.HasPrefix(comment, []byte("OpusTags")):
+		comment = comment[8:]
+	default:
+		return ErrNoVorbisComment
+	}
+	return readVorbisComment(comment, tags)
+}
+
+// readVorbisComment parses the little-endian vendor string and list of
+// "NAME=value" comments shared by FLAC, Vorbis and Opus.
+func readVorbisComment(data []byte, tags *Tags) error {
+	if len(data) < 4 {
+		return ErrNoVorbisComment
+	}
+	vendorLength := int(binary.LittleEndian.Uint32(data))
+	data = data[4:]
+	if vendorLength+4 > len(data) {
+		return ErrNoVorbisComment
+	}
+	data = data[vendorLength:]
+	count := int(binary.LittleEndian.Uint32(data))
+	data = data[4:]
+
+	for i := 0; i < count && len(data) >= 4; i++ {
+		length := int(binary.LittleEndian.Uint32(data))
+		data = data[4:]
+		if length > len(data) {
+			break
+		}
+		name, value, found := strings.Cut(string(data[:length]), "=")
+		data = data[length:]
+		if !found {
+			continue
+		}
+
+		switch strings.ToUpper(name) {
+		case "TITLE":
+			tags.Title = value
+		case "ARTIST":
+			tags.Artist = value
+		case "INITIALKEY", "KEY":
+			tags.Key = value
+		case "BPM", "TEMPO":
+			tags.setBPM(value)
+		case "COMMENT", "DESCRIPTION":
+			tags.addComment(value)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,149 @@
+package tags
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// vorbisComment builds the vendor string and comments FLAC, Vorbis and Opus
+// share.
+func vorbisComment(comments ...string) []byte {
+	vendor := "reference libFLAC 1.4.3"
+	data := binary.LittleEndian.AppendUint32(nil, uint32(len(vendor)))
+	data = append(data, vendor...)
+	data = binary.LittleEndian.AppendUint32(data, uint32(len(comments)))
+	for _, comment := range comments {
+		data = binary.LittleEndian.AppendUint32(data, uint32(len(comment)))
+		data = append(data, comment...)
+	}
+	return data
+}
+
+var mixedInKeyComments = []string{"TITLE=Title", "artist=Artist", "INITIALKEY=8A", "BPM=124", "COMMENT=8A - Energy 6"}
+
+func flacBlock(blockType byte, last bool, data []byte) []byte {
+	if last {
+		blockType |= flacLastBlock
+	}
+	return append([]byte{blockType, byte(len(data) >> 16), byte(len(data) >> 8), byte(len(data))}, data...)
+}
+
+func flacFile(blocks ...[]byte) []byte {
+	return append([]byte("fLaC"), bytes.Join(blocks, nil)...)
+}
+
+func TestReadFlac(t *testing.T) {
+	streamInfo := flacBlock(0, false, make([]byte, 34))
+	tests := []struct {
+		name    string
+		file    []byte
+		want    Tags
+		wantErr error
+	}{
+		{"comment block", flacFile(streamInfo, flacBlock(flacVorbisComment, true, vorbisComment(mixedInKeyComments...))), mixedInKeyTags, nil},
+		{"after padding and pictures", flacFile(streamInfo, flacBlock(1, false, make([]byte, 1024)), flacBlock(6, false, make([]byte, 300)),
+			flacBlock(flacVorbisComment, true, vorbisComment("KEY=Am", "TEMPO=127.5", "DESCRIPTION=Energy 7", "COMMENT=Peak"))),
+			Tags{Key: "Am", BPM: 127.5, Comment: "Energy 7\nPeak"}, nil},
+		{"comments without a value", flacFile(streamInfo, flacBlock(flacVorbisComment, true, vorbisComment("TITLE", "ARTIST=A=B", "BPM=fast"))), Tags{Artist: "A=B"}, nil},
+		{"comment longer than the block", flacFile(streamInfo, flacBlock(flacVorbisComment, true,
+			append(vorbisComment("TITLE=Title", "ARTIST=Artist")[:len(vorbisComment("TITLE=Title"))], "\xff\xff\xff\x7fARTIST=Artist"...))),
+			Tags{Title: "Title"}, nil},
+		{"more comments than the block holds", flacFile(streamInfo, flacBlock(flacVorbisComment, true,
+			append(binary.LittleEndian.AppendUint32(vorbisComment()[:len(vorbisComment())-4], 1000), vorbisComment("TITLE=Title")[len(vorbisComment()):]...))),
+			Tags{Title: "Title"}, nil},
+		{"vendor longer than the block", flacFile(streamInfo, flacBlock(flacVorbisComment, true, []byte("\xff\xff\xff\x7fvendor\x00\x00\x00\x00"))), Tags{}, ErrNoVorbisComment},
+		{"block too short", flacFile(streamInfo, flacBlock(flacVorbisComment, true, []byte{1, 0})), Tags{}, ErrNoVorbisComment},
+		{"no comment block", flacFile(flacBlock(0, true, make([]byte, 34))), Tags{}, ErrNoVorbisComment},
+		{"block past the file", flacFile(streamInfo, flacBlock(flacVorbisComment, true, vorbisComment(mixedInKeyComments...))[:40]), Tags{}, io.ErrUnexpectedEOF},
+		{"last block missing", flacFile(streamInfo), Tags{}, io.EOF},
+		{"not FLAC", []byte("ID3\x04\x00\x00\x00\x00"), Tags{}, ErrNoVorbisComment},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tags := Tags{}
+			err := readFlac(bytes.NewReader(tt.file), &tags)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("readFlac() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(tags, tt.want) {
+				t.Errorf("readFlac() = %+v, want %+v", tags, tt.want)
+			}
+		})
+	}
+}
+
+// oggPages splits packets into pages of at most segments segments each, the
+// way an encoder laces them: 255 byte segments and a shorter one ending each
+// packet.
+func oggPages(segments int, packets ...[]byte) []byte {
+	lacing := []byte{}
+	data := []byte{}
+	for _, packet := range packets {
+		for len(packet) >= 255 {
+			lacing = append(lacing, 255)
+			packet = packet[255:]
+		}
+		lacing = append(lacing, byte(len(packet)))
+	}
+	for _, packet := range packets {
+		data = append(data, packet...)
+	}
+
+	file := []byte{}
+	for sequence := 0; len(lacing) > 0; sequence++ {
+		n := min(segments, len(lacing))
+		size := 0
+		for _, length := range lacing[:n] {
+			size += int(length)
+		}
+		header := append([]byte("OggS"), make([]byte, 22)...)
+		binary.LittleEndian.PutUint32(header[18:], uint32(sequence))
+		header = append(header, byte(n))
+		file = append(file, header...)
+		file = append(file, lacing[:n]...)
+		file = append(file, data[:size]...)
+		lacing, data = lacing[n:], data[size:]
+	}
+	return file
+}
+
+func TestReadOgg(t *testing.T) {
+	vorbisHead := append([]byte("\x01vorbis"), make([]byte, 23)...)
+	vorbisTags := append([]byte("\x03vorbis"), vorbisComment(mixedInKeyComments...)...)
+	opusHead := append([]byte("OpusHead"), make([]byte, 11)...)
+	opusTags := append([]byte("OpusTags"), vorbisComment(mixedInKeyComments...)...)
+	long := strings.Repeat("x", 1000)
+	longTags := append([]byte("\x03vorbis"), vorbisComment("TITLE="+long, "INITIALKEY=8A")...)
+	tests := []struct {
+		name    string
+		file    []byte
+		want    Tags
+		wantErr error
+	}{
+		{"Vorbis", oggPages(255, vorbisHead, vorbisTags, make([]byte, 100)), mixedInKeyTags, nil},
+		{"Opus", oggPages(255, opusHead, opusTags), mixedInKeyTags, nil},
+		{"a page per packet", append(oggPages(255, vorbisHead), oggPages(255, vorbisTags)...), mixedInKeyTags, nil},
+		{"comment across pages", oggPages(2, vorbisHead, longTags), Tags{Title: long, Key: "8A"}, nil},
+		{"no comment header", oggPages(255, vorbisHead, []byte("\x05vorbis")), Tags{}, ErrNoVorbisComment},
+		{"one packet", oggPages(255, vorbisHead), Tags{}, io.EOF},
+		{"truncated page", oggPages(255, vorbisHead, vorbisTags)[:60], Tags{}, io.ErrUnexpectedEOF},
+		{"not Ogg", []byte(strings.Repeat("RIFF", 10)), Tags{}, ErrNoVorbisComment},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tags := Tags{}
+			err := readOgg(bytes.NewReader(tt.file), &tags)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("readOgg() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(tags, tt.want) {
+				t.Errorf("readOgg() = %+v, want %+v", tags, tt.want)
+			}
+		})
+	}
+}
@@ -6,7 +6,9 @@ type StringSlice []string
 
 func (s StringSlice) ContainsAnyOf(other []string) bool {
 	for _, item := range other {
-		return slices.Contains(s, item)
+		if slices.Contains(s, item) {
+			return true
+		}
 	}
 	return false
 }
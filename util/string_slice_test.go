@@ -0,0 +1,55 @@
+package util
+
+import (
+	"slices"
+	"testing"
+	"testing/quick"
+)
+
+func TestStringSliceContainsAnyOf(t *testing.T) {
+	tests := []struct {
+		name  string
+		slice StringSlice
+		other []string
+		want  bool
+	}{
+		{"both empty", StringSlice{}, []string{}, false},
+		{"empty slice", StringSlice{}, []string{"house"}, false},
+		{"nothing to look for", StringSlice{"house"}, nil, false},
+		{"one match", StringSlice{"house", "techno"}, []string{"techno"}, true},
+		{"one of many", StringSlice{"house", "techno"}, []string{"trance", "house"}, true},
+		{"no match", StringSlice{"house", "techno"}, []string{"trance", "dnb"}, false},
+		{"case sensitive", StringSlice{"House"}, []string{"house"}, false},
+		{"empty tag", StringSlice{""}, []string{""}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.slice.ContainsAnyOf(tt.other); got != tt.want {
+				t.Errorf("%v.ContainsAnyOf(%v) = %v, want %v", tt.slice, tt.other, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStringSliceContainsAnyOfProperties(t *testing.T) {
+	// The same as looking each string up
+	matchesLookup := func(s []string, other []string) bool {
+		want := slices.ContainsFunc(other, func(o string) bool {
+			return slices.Contains(s, o)
+		})
+		return StringSlice(s).ContainsAnyOf(other) == want
+	}
+	// Symmetric
+	symmetric := func(s []string, other []string) bool {
+		return StringSlice(s).ContainsAnyOf(other) == StringSlice(other).ContainsAnyOf(s)
+	}
+	// Any non-empty slice contains any of itself
+	reflexive := func(s []string) bool {
+		return StringSlice(s).ContainsAnyOf(s) == (len(s) > 0)
+	}
+	for name, property := range map[string]any{"lookup": matchesLookup, "symmetric": symmetric, "reflexive": reflexive} {
+		if err := quick.Check(property, nil); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}